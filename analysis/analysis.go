@@ -0,0 +1,437 @@
+// Package analysis answers structural questions about state machine definitions,
+// like "can a subject get from one state to another?" or "which states can never be left?".
+//
+// The analysis follows the semantics of the state machine:
+// transitions declared on a parent state apply to all of its descendants
+// (unless an unguarded transition of an inner state or an explicit transition triggered by the same event takes precedence),
+// targeting a composite state enters its initial state (or every region of a parallel state)
+// and targeting a history pseudo-state enters its default state.
+// Guards are expected to pass eventually, so guarded transitions are considered as any other transition.
+// Transitions with multiple source states and wildcard transitions are considered for every state they can be triggered in.
+package analysis
+
+import (
+	"sort"
+
+	"github.com/goph/fsm"
+)
+
+// Analysis analyzes a state machine definition.
+type Analysis struct {
+	def fsm.Definition
+
+	names    []string
+	order    map[string]int
+	states   map[string]fsm.State
+	children map[string][]string
+
+	// ancestors holds the states themselves followed by their ancestors from the innermost to the outermost one
+	ancestors map[string][]string
+
+	// targets holds the active states entered by targeting the states
+	targets map[string][]string
+
+	// from holds the indexes of the transitions by source state
+	from map[string][]int
+
+	edges   map[string][]edge
+	reverse map[string][]edge
+}
+
+// edge is a transition between two active states.
+type edge struct {
+	transition int
+	state      string
+}
+
+// New analyzes a state machine definition.
+//
+//	a := analysis.New(sm.Definition())
+func New(def fsm.Definition) *Analysis {
+	a := &Analysis{
+		def:       def,
+		names:     def.StateNames(),
+		order:     make(map[string]int),
+		states:    make(map[string]fsm.State),
+		children:  make(map[string][]string),
+		ancestors: make(map[string][]string),
+		targets:   make(map[string][]string),
+		from:      make(map[string][]int),
+		edges:     make(map[string][]edge),
+		reverse:   make(map[string][]edge),
+	}
+
+	for i, name := range a.names {
+		a.order[name] = i
+		a.states[name] = def.State(name)
+		a.children[name] = def.Children(name)
+		a.ancestors[name] = append([]string{name}, def.Ancestors(name)...)
+		a.targets[name] = def.Targets(name)
+	}
+
+	// Wildcard transitions are expanded to the states they can be triggered in
+	for i, t := range def.Transitions {
+		for _, source := range def.Sources(t) {
+			a.from[source] = append(a.from[source], i)
+		}
+	}
+
+	for _, name := range a.names {
+		if !a.isActive(name) {
+			continue
+		}
+
+		for _, ancestor := range a.ancestors[name] {
+			for _, i := range a.from[ancestor] {
+				// Transitions of inner states and explicit transitions take precedence
+				if def.Preempted(i, name) {
+					continue
+				}
+
+				for _, target := range a.resolve(def.Transitions[i].ToState) {
+					a.edges[name] = append(a.edges[name], edge{i, target})
+					a.reverse[target] = append(a.reverse[target], edge{i, name})
+				}
+			}
+		}
+	}
+
+	return a
+}
+
+// isActive checks whether a state can be the innermost active state (of a region).
+func (a *Analysis) isActive(state string) bool {
+	s := a.states[state]
+
+	if s.History != "" {
+		return false
+	}
+
+	return len(a.children[state]) == 0 || (!s.Parallel && s.Initial == "")
+}
+
+// isAncestor checks whether a state is a proper ancestor of another one.
+func (a *Analysis) isAncestor(ancestor string, state string) bool {
+	ancestors := a.ancestors[state]
+
+	for i := 1; i < len(ancestors); i++ {
+		if ancestors[i] == ancestor {
+			return true
+		}
+	}
+
+	return false
+}
+
+// resolve returns the active states entered by targeting a state.
+func (a *Analysis) resolve(state string) []string {
+	targets, ok := a.targets[state]
+	if !ok {
+		return []string{state}
+	}
+
+	return append([]string(nil), targets...)
+}
+
+// within checks whether a state is the same as or a descendant of another one.
+func (a *Analysis) within(state string, ancestor string) bool {
+	return state == ancestor || a.isAncestor(ancestor, state)
+}
+
+// sorted returns a set of states in declaration order.
+func (a *Analysis) sorted(set map[string]bool) []string {
+	states := make([]string, 0, len(set))
+	for state := range set {
+		states = append(states, state)
+	}
+
+	sort.Slice(states, func(i, j int) bool {
+		return a.order[states[i]] < a.order[states[j]]
+	})
+
+	return states
+}
+
+// withAncestors adds the ancestors of the states to a set of states.
+func (a *Analysis) withAncestors(set map[string]bool) map[string]bool {
+	result := make(map[string]bool, len(set))
+
+	for state := range set {
+		result[state] = true
+
+		for _, s := range a.ancestors[state] {
+			result[s] = true
+		}
+	}
+
+	return result
+}
+
+// Reachable returns the states which can be active after entering a state (including the state itself) in declaration order.
+func (a *Analysis) Reachable(state string) []string {
+	visited := make(map[string]bool)
+
+	queue := a.resolve(state)
+	for _, s := range queue {
+		visited[s] = true
+	}
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		for _, e := range a.edges[current] {
+			if !visited[e.state] {
+				visited[e.state] = true
+				queue = append(queue, e.state)
+			}
+		}
+	}
+
+	return a.sorted(a.withAncestors(visited))
+}
+
+// CoReachable returns the states from which a state can be reached (including the state itself) in declaration order.
+func (a *Analysis) CoReachable(state string) []string {
+	visited := make(map[string]bool)
+
+	var queue []string
+
+	for _, name := range a.names {
+		if a.isActive(name) && a.within(name, state) {
+			visited[name] = true
+			queue = append(queue, name)
+		}
+	}
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		for _, e := range a.reverse[current] {
+			if !visited[e.state] {
+				visited[e.state] = true
+				queue = append(queue, e.state)
+			}
+		}
+	}
+
+	result := make(map[string]bool)
+
+	for _, name := range a.names {
+		// History pseudo-states are never active
+		if a.states[name].History != "" {
+			continue
+		}
+
+		if a.within(name, state) {
+			result[name] = true
+
+			continue
+		}
+
+		for _, target := range a.resolve(name) {
+			if visited[target] {
+				result[name] = true
+			}
+		}
+	}
+
+	return a.sorted(result)
+}
+
+// DeadEnds returns the states which can never be left once they are entered in declaration order.
+//
+// Only states which can be active without active children are considered.
+// Self transitions don't leave a state for good, so they are ignored.
+// Final states are expected to be dead ends.
+func (a *Analysis) DeadEnds() []string {
+	var deadEnds []string
+
+	for _, name := range a.names {
+		if !a.isActive(name) {
+			continue
+		}
+
+		left := false
+
+		for _, e := range a.edges[name] {
+			if e.state != name {
+				left = true
+
+				break
+			}
+		}
+
+		if !left {
+			deadEnds = append(deadEnds, name)
+		}
+	}
+
+	return deadEnds
+}
+
+// StronglyConnectedComponents returns the groups of states which can all be reached from each other.
+//
+// Only states which can be active without active children are considered.
+// Components are ordered by their first state in declaration order (as are the states of a component).
+func (a *Analysis) StronglyConnectedComponents() [][]string {
+	// Tarjan's algorithm
+	var (
+		index    int
+		indexes  = make(map[string]int)
+		lowlinks = make(map[string]int)
+		onStack  = make(map[string]bool)
+		stack    []string
+
+		components [][]string
+	)
+
+	var connect func(state string)
+	connect = func(state string) {
+		indexes[state] = index
+		lowlinks[state] = index
+		index++
+
+		stack = append(stack, state)
+		onStack[state] = true
+
+		for _, e := range a.edges[state] {
+			if _, ok := indexes[e.state]; !ok {
+				connect(e.state)
+
+				if lowlinks[e.state] < lowlinks[state] {
+					lowlinks[state] = lowlinks[e.state]
+				}
+			} else if onStack[e.state] && indexes[e.state] < lowlinks[state] {
+				lowlinks[state] = indexes[e.state]
+			}
+		}
+
+		if lowlinks[state] != indexes[state] {
+			return
+		}
+
+		component := make(map[string]bool)
+
+		for {
+			s := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[s] = false
+			component[s] = true
+
+			if s == state {
+				break
+			}
+		}
+
+		components = append(components, a.sorted(component))
+	}
+
+	for _, name := range a.names {
+		if _, ok := indexes[name]; !ok && a.isActive(name) {
+			connect(name)
+		}
+	}
+
+	sort.Slice(components, func(i, j int) bool {
+		return a.order[components[i][0]] < a.order[components[j][0]]
+	})
+
+	return components
+}
+
+// ShortestPath returns the shortest sequence of transitions leading from one state to another.
+//
+// The events of the transitions can be triggered in order to get from one state to the other.
+// It returns false if the state cannot be reached.
+func (a *Analysis) ShortestPath(from string, to string) ([]fsm.Transition, bool) {
+	type step struct {
+		previous   string
+		transition int
+	}
+
+	steps := make(map[string]step)
+	visited := make(map[string]bool)
+
+	queue := a.resolve(from)
+	for _, s := range queue {
+		visited[s] = true
+	}
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		if a.within(current, to) {
+			var path []fsm.Transition
+
+			for state := current; ; {
+				s, ok := steps[state]
+				if !ok {
+					break
+				}
+
+				path = append([]fsm.Transition{a.def.Transitions[s.transition]}, path...)
+				state = s.previous
+			}
+
+			return path, true
+		}
+
+		for _, e := range a.edges[current] {
+			if !visited[e.state] {
+				visited[e.state] = true
+				steps[e.state] = step{current, e.transition}
+				queue = append(queue, e.state)
+			}
+		}
+	}
+
+	return nil, false
+}
+
+// Incoming returns the transitions entering a state in declaration order.
+//
+// Transitions targeting an ancestor (entering the state as an initial state)
+// or a history pseudo-state of an ancestor (possibly restoring the state) enter the state as well.
+func (a *Analysis) Incoming(state string) []fsm.Transition {
+	var transitions []fsm.Transition
+
+	for i, t := range a.def.Transitions {
+		for _, source := range a.def.Sources(t) {
+			if a.def.Preempted(i, source) {
+				continue
+			}
+
+			if a.enters(t.ToState, a.def.Domain(source, t.ToState), state) {
+				transitions = append(transitions, t)
+
+				break
+			}
+		}
+	}
+
+	return transitions
+}
+
+// enters checks whether targeting a state (below a transition domain) enters another state.
+func (a *Analysis) enters(target string, domain string, state string) bool {
+	// The state is an ancestor of the target (below the domain)
+	if a.within(target, state) && (domain == "" || a.isAncestor(domain, state)) {
+		return true
+	}
+
+	// History pseudo-states may restore any descendant of their parent
+	if s := a.states[target]; s.History != "" {
+		return a.isAncestor(s.Parent, state)
+	}
+
+	for _, resolved := range a.resolve(target) {
+		if a.within(resolved, state) && a.isAncestor(target, state) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,199 @@
+package analysis_test
+
+import (
+	"testing"
+
+	"github.com/goph/fsm"
+	"github.com/goph/fsm/analysis"
+	"github.com/stretchr/testify/assert"
+)
+
+func order() fsm.Definition {
+	return fsm.Definition{
+		InitialState: "new",
+		States: []fsm.State{
+			{Name: "new"},
+			{Name: "paid"},
+			{Name: "in_fulfilment", Initial: "picking"},
+			{Name: "picking", Parent: "in_fulfilment"},
+			{Name: "packing", Parent: "in_fulfilment"},
+			{Name: "in_fulfilment_history", Parent: "in_fulfilment", History: fsm.DeepHistory},
+			{Name: "on_hold"},
+			{Name: "shipped"},
+			{Name: "returned"},
+			{Name: "refunded", Final: true},
+			{Name: "cancelled", Final: true},
+			{Name: "archived"},
+		},
+		Transitions: []fsm.Transition{
+			{FromState: "new", Event: "pay", ToState: "paid"},
+			{FromState: "paid", Event: "fulfil", ToState: "in_fulfilment"},
+			{FromState: "paid", Event: "refund", ToState: "refunded"},
+			{FromState: "picking", Event: "pick", ToState: "packing"},
+			{FromState: "packing", Event: "pack", ToState: "shipped"},
+			{FromState: "in_fulfilment", Event: "cancel", ToState: "cancelled"},
+			{FromState: "in_fulfilment", Event: "hold", ToState: "on_hold"},
+			{FromState: "on_hold", Event: "resume", ToState: "in_fulfilment_history"},
+			{FromState: "shipped", Event: "return", ToState: "returned"},
+			{FromState: "returned", Event: "refund", ToState: "refunded"},
+			{FromState: "archived", Event: "restore", ToState: "new"},
+			{FromState: "cancelled", Event: "cancel", ToState: "cancelled"},
+		},
+	}
+}
+
+func TestAnalysis_Reachable(t *testing.T) {
+	a := analysis.New(order())
+
+	assert.Equal(
+		t,
+		[]string{"paid", "in_fulfilment", "picking", "packing", "on_hold", "shipped", "returned", "refunded", "cancelled"},
+		a.Reachable("paid"),
+	)
+	assert.Equal(t, []string{"in_fulfilment", "picking", "packing", "on_hold", "shipped", "returned", "refunded", "cancelled"}, a.Reachable("in_fulfilment"))
+	assert.Equal(t, []string{"cancelled"}, a.Reachable("cancelled"))
+}
+
+func TestAnalysis_CoReachable(t *testing.T) {
+	a := analysis.New(order())
+
+	assert.Equal(
+		t,
+		[]string{"new", "paid", "in_fulfilment", "picking", "packing", "on_hold", "shipped", "returned", "refunded", "archived"},
+		a.CoReachable("refunded"),
+	)
+	assert.Equal(
+		t,
+		[]string{"new", "paid", "in_fulfilment", "picking", "packing", "on_hold", "archived"},
+		a.CoReachable("in_fulfilment"),
+	)
+}
+
+func TestAnalysis_DeadEnds(t *testing.T) {
+	a := analysis.New(order())
+
+	assert.Equal(t, []string{"refunded", "cancelled"}, a.DeadEnds())
+}
+
+func TestAnalysis_StronglyConnectedComponents(t *testing.T) {
+	a := analysis.New(order())
+
+	assert.Equal(
+		t,
+		[][]string{
+			{"new"},
+			{"paid"},
+			{"picking", "packing", "on_hold"},
+			{"shipped"},
+			{"returned"},
+			{"refunded"},
+			{"cancelled"},
+			{"archived"},
+		},
+		a.StronglyConnectedComponents(),
+	)
+}
+
+func TestAnalysis_ShortestPath(t *testing.T) {
+	def := order()
+	a := analysis.New(def)
+
+	path, ok := a.ShortestPath("new", "refunded")
+	assert.True(t, ok)
+	assert.Equal(t, []fsm.Transition{def.Transitions[0], def.Transitions[2]}, path)
+
+	path, ok = a.ShortestPath("on_hold", "shipped")
+	assert.True(t, ok)
+	assert.Equal(t, []fsm.Transition{def.Transitions[7], def.Transitions[3], def.Transitions[4]}, path)
+
+	path, ok = a.ShortestPath("packing", "in_fulfilment")
+	assert.True(t, ok)
+	assert.Empty(t, path)
+
+	_, ok = a.ShortestPath("cancelled", "new")
+	assert.False(t, ok)
+}
+
+func TestAnalysis_Incoming(t *testing.T) {
+	def := order()
+	a := analysis.New(def)
+
+	assert.Equal(t, []fsm.Transition{def.Transitions[1], def.Transitions[7]}, a.Incoming("picking"))
+	assert.Equal(t, []fsm.Transition{def.Transitions[3], def.Transitions[7]}, a.Incoming("packing"))
+	assert.Equal(t, []fsm.Transition{def.Transitions[1], def.Transitions[7]}, a.Incoming("in_fulfilment"))
+	assert.Equal(t, []fsm.Transition{def.Transitions[5], def.Transitions[11]}, a.Incoming("cancelled"))
+	assert.Empty(t, a.Incoming("archived"))
+}
+
+func TestAnalysis_Wildcards(t *testing.T) {
+	def := fsm.Definition{
+		InitialState: "new",
+		States: []fsm.State{
+			{Name: "in_fulfilment", Initial: "picking"},
+			{Name: "picking", Parent: "in_fulfilment"},
+			{Name: "packing", Parent: "in_fulfilment"},
+			{Name: "shipped", Final: true},
+			{Name: "cancelled", Final: true},
+		},
+		Transitions: []fsm.Transition{
+			{FromStates: []string{"new", "on_hold"}, Event: "pay", ToState: "in_fulfilment"},
+			{FromState: "picking", Event: "pick", ToState: "packing"},
+			{FromState: "packing", Event: "ship", ToState: "shipped"},
+			{FromState: fsm.AnyState, ExceptStates: []string{"packing", "shipped", "cancelled"}, Event: "cancel", ToState: "cancelled"},
+		},
+	}
+
+	a := analysis.New(def)
+
+	assert.Equal(t, []string{"in_fulfilment", "picking", "packing", "shipped", "cancelled", "new"}, a.Reachable("new"))
+	assert.Equal(t, []string{"shipped", "cancelled"}, a.DeadEnds())
+
+	path, ok := a.ShortestPath("picking", "cancelled")
+
+	assert.True(t, ok)
+	assert.Equal(t, []fsm.Transition{def.Transitions[3]}, path)
+
+	assert.Equal(t, []fsm.Transition{def.Transitions[0]}, a.Incoming("picking"))
+	assert.Equal(t, []fsm.Transition{def.Transitions[3]}, a.Incoming("cancelled"))
+}
+
+func TestAnalysis_Precedence(t *testing.T) {
+	def := fsm.Definition{
+		InitialState: "new",
+		States: []fsm.State{
+			{Name: "in_fulfilment", Initial: "picking"},
+			{Name: "picking", Parent: "in_fulfilment"},
+			{Name: "packing", Parent: "in_fulfilment"},
+		},
+		Transitions: []fsm.Transition{
+			{FromState: "new", Event: "pay", ToState: "in_fulfilment"},
+			{FromState: "new", Event: "cancel", ToState: "rejected"},
+			{FromState: "picking", Event: "pick", ToState: "packing"},
+			{FromState: "picking", Event: "cancel", ToState: "restocking"},
+			{FromState: "packing", Event: "cancel", Guard: "not_sealed", ToState: "restocking"},
+			{FromState: "in_fulfilment", Event: "cancel", ToState: "refunded"},
+			{FromState: fsm.AnyState, ExceptStates: []string{"rejected", "restocking", "refunded", "cancelled"}, Event: "cancel", ToState: "cancelled"},
+		},
+	}
+
+	a := analysis.New(def)
+	sm := fsm.NewStateMachine(nil, def.Transitions, def.Options()...)
+
+	// Explicit transitions take precedence over wildcard transitions
+	transition, err := sm.DryRun("new", "cancel")
+	assert.NoError(t, err)
+	assert.Equal(t, "rejected", transition.ToState)
+
+	_, ok := a.ShortestPath("new", "cancelled")
+	assert.False(t, ok)
+	assert.Empty(t, a.Incoming("cancelled"))
+
+	// Transitions of inner states take precedence over transitions of their ancestors (unless they are guarded)
+	transition, err = sm.DryRun("picking", "cancel")
+	assert.NoError(t, err)
+	assert.Equal(t, "restocking", transition.ToState)
+
+	path, ok := a.ShortestPath("picking", "refunded")
+	assert.True(t, ok)
+	assert.Equal(t, []fsm.Transition{def.Transitions[2], def.Transitions[5]}, path)
+}
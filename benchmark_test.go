@@ -0,0 +1,149 @@
+package fsm_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/goph/fsm"
+	"github.com/stretchr/testify/assert"
+)
+
+// nopDelegate is a delegate doing nothing, so that benchmarks measure the state machine only.
+type nopDelegate struct{}
+
+func (d nopDelegate) Handle(action string, fromState string, toState string, args []interface{}) error {
+	return nil
+}
+
+// benchmarkSubject is a subject with a fixed state.
+type benchmarkSubject struct {
+	state string
+}
+
+func (s *benchmarkSubject) GetState() string {
+	return s.state
+}
+
+// generateTransitions generates a transition table of states with the given number of events each.
+func generateTransitions(states int, events int) []fsm.Transition {
+	transitions := make([]fsm.Transition, 0, states*events)
+
+	for s := 0; s < states; s++ {
+		for e := 0; e < events; e++ {
+			transitions = append(transitions, fsm.Transition{
+				FromState: fmt.Sprintf("state_%d", s),
+				Event:     fmt.Sprintf("event_%d", e),
+				ToState:   fmt.Sprintf("state_%d", (s+e+1)%states),
+				Action:    fmt.Sprintf("action_%d", e),
+			})
+		}
+	}
+
+	return transitions
+}
+
+func TestStateMachine_Trigger_ZeroAllocs(t *testing.T) {
+	sm := fsm.NewStateMachine(nopDelegate{}, generateTransitions(10, 10))
+
+	allocs := testing.AllocsPerRun(100, func() {
+		sm.Trigger("state_9", "event_9")
+	})
+
+	assert.Zero(t, allocs)
+}
+
+func BenchmarkStateMachine_Trigger(b *testing.B) {
+	sizes := []struct {
+		states int
+		events int
+	}{
+		{2, 2},
+		{50, 20},
+		{1000, 5},
+	}
+
+	for _, size := range sizes {
+		transitions := generateTransitions(size.states, size.events)
+		sm := fsm.NewStateMachine(nopDelegate{}, transitions)
+
+		// The last transition is the worst case for a linear scan
+		last := transitions[len(transitions)-1]
+
+		b.Run(fmt.Sprintf("%d transitions", len(transitions)), func(b *testing.B) {
+			b.ReportAllocs()
+
+			for i := 0; i < b.N; i++ {
+				sm.Trigger(last.FromState, last.Event)
+			}
+		})
+	}
+}
+
+func BenchmarkStateMachine_Trigger_Arguments(b *testing.B) {
+	sm := fsm.NewStateMachine(nopDelegate{}, generateTransitions(2, 2))
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		sm.Trigger("state_0", "event_0", "argument", 1)
+	}
+}
+
+func BenchmarkStateMachine_Trigger_Invalid(b *testing.B) {
+	sm := fsm.NewStateMachine(nopDelegate{}, generateTransitions(2, 2))
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		sm.Trigger("state_0", "unknown_event")
+	}
+}
+
+func BenchmarkStateMachine_Trigger_Nested(b *testing.B) {
+	states := []fsm.State{
+		{Name: "parent", Initial: "child", OnEnter: "enter", OnExit: "exit"},
+		{Name: "child", Parent: "parent", OnEnter: "enter", OnExit: "exit"},
+	}
+	transitions := []fsm.Transition{
+		{FromState: "parent", Event: "event", ToState: "parent", Action: "action"},
+	}
+
+	sm := fsm.NewStateMachine(nopDelegate{}, transitions, fsm.WithStates(states))
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		sm.Trigger("child", "event")
+	}
+}
+
+func BenchmarkStateMachine_TriggerSubject(b *testing.B) {
+	sm := fsm.NewStateMachine(nopDelegate{}, generateTransitions(2, 2))
+	subject := &benchmarkSubject{"state_0"}
+
+	b.Run("without arguments", func(b *testing.B) {
+		b.ReportAllocs()
+
+		for i := 0; i < b.N; i++ {
+			sm.TriggerSubject(subject, "event_0")
+		}
+	})
+
+	b.Run("with arguments", func(b *testing.B) {
+		b.ReportAllocs()
+
+		for i := 0; i < b.N; i++ {
+			sm.TriggerSubject(subject, "event_0", "argument", 1)
+		}
+	})
+}
+
+func BenchmarkNewStateMachine(b *testing.B) {
+	transitions := generateTransitions(1000, 5)
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		fsm.NewStateMachine(nopDelegate{}, transitions)
+	}
+}
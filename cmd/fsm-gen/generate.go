@@ -0,0 +1,346 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/goph/fsm"
+)
+
+// config configures the generated code.
+type config struct {
+	// source is the name of the definition file.
+	source string
+
+	packageName string
+	stateType   string
+	eventType   string
+
+	// constructor is the name of the state machine constructor (none is generated if empty).
+	constructor string
+
+	// subjectType is the name of the subject type receiving a method per event (none are generated if empty).
+	subjectType string
+
+	// field is the field of the subject type holding the state machine.
+	field string
+
+	// methods overrides the name of the method generated for an event.
+	methods map[string]string
+}
+
+// generator writes Go code for a definition.
+type generator struct {
+	config config
+	def    fsm.Definition
+	buf    bytes.Buffer
+
+	states map[string]string
+	events map[string]string
+}
+
+// generate returns the formatted Go code for a definition.
+func generate(def fsm.Definition, cfg config) ([]byte, error) {
+	g := &generator{
+		config: cfg,
+		def:    def,
+		states: make(map[string]string),
+		events: make(map[string]string),
+	}
+
+	if err := g.generate(); err != nil {
+		return nil, err
+	}
+
+	src, err := format.Source(g.buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("generated invalid code: %s", err)
+	}
+
+	return src, nil
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+func (g *generator) generate() error {
+	g.printf("// Code generated by fsm-gen from %s. DO NOT EDIT.\n\n", g.config.source)
+	g.printf("package %s\n\n", g.config.packageName)
+
+	if g.config.constructor != "" || g.config.subjectType != "" {
+		g.printf("import \"github.com/goph/fsm\"\n\n")
+	}
+
+	if err := g.generateConstants(); err != nil {
+		return err
+	}
+
+	if g.config.constructor != "" {
+		g.generateConstructor()
+	}
+
+	if g.config.subjectType != "" {
+		if err := g.generateMethods(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// eventNames returns every event of the definition in order.
+func (g *generator) eventNames() []string {
+	var events []string
+
+	seen := make(map[string]bool)
+
+	for _, t := range g.def.Transitions {
+		if t.Event != "" && !seen[t.Event] {
+			seen[t.Event] = true
+			events = append(events, t.Event)
+		}
+	}
+
+	return events
+}
+
+// generateConstants writes the state and event types and constants.
+func (g *generator) generateConstants() error {
+	blocks := []struct {
+		typ      string
+		kind     string
+		plural   string
+		names    []string
+		mapping  map[string]string
+		receiver string
+	}{
+		{g.config.stateType, "state", "States", g.def.StateNames(), g.states, "s"},
+		{g.config.eventType, "event", "Events", g.eventNames(), g.events, "e"},
+	}
+
+	for _, block := range blocks {
+		constants := make(map[string]string)
+
+		for _, name := range block.names {
+			constant := block.typ + camelCase(name)
+			if other, ok := constants[constant]; ok {
+				return fmt.Errorf("%ss %q and %q would have the same constant name %s", block.kind, other, name, constant)
+			}
+
+			constants[constant] = name
+			block.mapping[name] = constant
+		}
+
+		g.printf("// %s is a%s %s of the state machine.\n", block.typ, article(block.kind), block.kind)
+		g.printf("type %s string\n\n", block.typ)
+
+		g.printf("// %s of the state machine.\n", block.plural)
+		g.printf("const (\n")
+		for _, name := range block.names {
+			g.printf("%s %s = %q\n", block.mapping[name], block.typ, name)
+		}
+		g.printf(")\n\n")
+
+		g.printf("// String returns the name of the %s.\n", block.kind)
+		g.printf("func (%s %s) String() string {\n", block.receiver, block.typ)
+		g.printf("return string(%s)\n", block.receiver)
+		g.printf("}\n\n")
+	}
+
+	return nil
+}
+
+// generateConstructor writes a function returning a state machine for the definition.
+func (g *generator) generateConstructor() {
+	g.printf("// %s returns a new state machine for the definition in %s.\n", g.config.constructor, g.config.source)
+	g.printf("func %s(delegate fsm.Delegate, opts ...fsm.Option) *fsm.StateMachine {\n", g.config.constructor)
+	g.printf("return fsm.NewStateMachine(\n")
+	g.printf("delegate,\n")
+
+	g.printf("[]fsm.Transition{\n")
+	for _, t := range g.def.Transitions {
+		g.printf("{\n")
+
+		switch t.FromState {
+		case fsm.AnyState:
+			g.printf("FromState: fsm.AnyState,\n")
+
+		case "":
+
+		default:
+			g.printf("FromState: %s.String(),\n", g.states[t.FromState])
+		}
+
+		g.stateList("FromStates", t.FromStates)
+		g.stateList("ExceptStates", t.ExceptStates)
+
+		g.printf("Event: %s.String(),\n", g.events[t.Event])
+		g.printf("ToState: %s.String(),\n", g.states[t.ToState])
+		g.field("Action", t.Action)
+
+		if len(t.Actions) > 0 {
+			g.printf("Actions: %#v,\n", t.Actions)
+		}
+
+		g.field("Guard", t.Guard)
+
+		if len(t.Metadata) > 0 {
+			keys := make([]string, 0, len(t.Metadata))
+			for key := range t.Metadata {
+				keys = append(keys, key)
+			}
+
+			sort.Strings(keys)
+
+			g.printf("Metadata: map[string]string{\n")
+			for _, key := range keys {
+				g.printf("%q: %q,\n", key, t.Metadata[key])
+			}
+			g.printf("},\n")
+		}
+
+		g.printf("},\n")
+	}
+	g.printf("},\n")
+
+	g.printf("append(\n")
+	g.printf("[]fsm.Option{\n")
+
+	if g.def.InitialState != "" {
+		g.printf("fsm.WithInitialState(%s.String()),\n", g.states[g.def.InitialState])
+	}
+
+	if len(g.def.States) > 0 {
+		g.printf("fsm.WithStates([]fsm.State{\n")
+		for _, s := range g.def.States {
+			g.printf("{\n")
+			g.printf("Name: %s.String(),\n", g.states[s.Name])
+			g.field("OnEnter", s.OnEnter)
+			g.field("OnExit", s.OnExit)
+
+			if s.Parent != "" {
+				g.printf("Parent: %s.String(),\n", g.states[s.Parent])
+			}
+
+			if s.Initial != "" {
+				g.printf("Initial: %s.String(),\n", g.states[s.Initial])
+			}
+
+			if s.Parallel {
+				g.printf("Parallel: true,\n")
+			}
+
+			switch s.History {
+			case "":
+
+			case fsm.ShallowHistory:
+				g.printf("History: fsm.ShallowHistory,\n")
+
+			case fsm.DeepHistory:
+				g.printf("History: fsm.DeepHistory,\n")
+
+			default:
+				g.printf("History: %q,\n", s.History)
+			}
+
+			if s.Final {
+				g.printf("Final: true,\n")
+			}
+
+			g.printf("},\n")
+		}
+		g.printf("}),\n")
+	}
+
+	g.printf("},\n")
+	g.printf("opts...,\n")
+	g.printf(")...,\n")
+	g.printf(")\n")
+	g.printf("}\n\n")
+}
+
+// field writes a string field of a composite literal if it's not empty.
+func (g *generator) field(name string, value string) {
+	if value != "" {
+		g.printf("%s: %q,\n", name, value)
+	}
+}
+
+// stateList writes a field holding a list of states if it's not empty.
+func (g *generator) stateList(name string, states []string) {
+	if len(states) == 0 {
+		return
+	}
+
+	constants := make([]string, len(states))
+	for i, state := range states {
+		constants[i] = g.states[state] + ".String()"
+	}
+
+	g.printf("%s: []string{%s},\n", name, strings.Join(constants, ", "))
+}
+
+// generateMethods writes a method per event triggering the event for the subject.
+func (g *generator) generateMethods() error {
+	receiver := strings.ToLower(g.config.subjectType[:1])
+	methods := make(map[string]string)
+
+	for _, event := range g.eventNames() {
+		method, ok := g.config.methods[event]
+		if !ok {
+			method = camelCase(event)
+		}
+
+		if method == "" || !unicode.IsUpper([]rune(method)[0]) {
+			return fmt.Errorf("event %q has no valid exported method name (use -method %s=Name)", event, event)
+		}
+
+		if other, ok := methods[method]; ok {
+			return fmt.Errorf("events %q and %q would have the same method name %s", other, event, method)
+		}
+
+		methods[method] = event
+
+		g.printf("// %s triggers the %s event for the %s.\n", method, event, g.config.subjectType)
+		g.printf("func (%s *%s) %s(args ...interface{}) error {\n", receiver, g.config.subjectType, method)
+		g.printf("return %s.%s.TriggerSubject(%s, %s.String(), args...)\n", receiver, g.config.field, receiver, g.events[event])
+		g.printf("}\n\n")
+	}
+
+	for event := range g.config.methods {
+		if _, ok := g.events[event]; !ok {
+			return fmt.Errorf("unknown event %q in method names", event)
+		}
+	}
+
+	return nil
+}
+
+// camelCase converts a name to an exported identifier (eg. coin_inserted to CoinInserted).
+func camelCase(name string) string {
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	for i, word := range words {
+		runes := []rune(word)
+		runes[0] = unicode.ToUpper(runes[0])
+		words[i] = string(runes)
+	}
+
+	return strings.Join(words, "")
+}
+
+// article returns the indefinite article suffix of a word.
+func article(word string) string {
+	if strings.ContainsRune("aeiou", rune(word[0])) {
+		return "n"
+	}
+
+	return ""
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"testing"
+
+	"github.com/goph/fsm"
+	"github.com/goph/fsm/definition"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func TestGenerate(t *testing.T) {
+	def, err := definition.ReadFile("testdata/order.yaml")
+	require.NoError(t, err)
+
+	src, err := generate(def, config{
+		source:      "order.yaml",
+		packageName: "order",
+		stateType:   "State",
+		eventType:   "Event",
+		constructor: "NewStateMachine",
+		subjectType: "Order",
+		field:       "sm",
+		methods:     map[string]string{"pay": "Checkout"},
+	})
+	require.NoError(t, err)
+
+	if *update {
+		require.NoError(t, ioutil.WriteFile("testdata/order_fsm.go.golden", src, 0644))
+	}
+
+	expected, err := ioutil.ReadFile("testdata/order_fsm.go.golden")
+	require.NoError(t, err)
+
+	assert.Equal(t, string(expected), string(src))
+}
+
+func TestGenerate_Errors(t *testing.T) {
+	tests := map[string]struct {
+		transitions []fsm.Transition
+		methods     map[string]string
+		err         string
+	}{
+		"constant collision": {
+			transitions: []fsm.Transition{
+				{FromState: "on-hold", Event: "resume", ToState: "on_hold"},
+			},
+			err: "states \"on-hold\" and \"on_hold\" would have the same constant name StateOnHold",
+		},
+		"method collision": {
+			transitions: []fsm.Transition{
+				{FromState: "locked", Event: "push", ToState: "locked"},
+				{FromState: "locked", Event: "pushed", ToState: "locked"},
+			},
+			methods: map[string]string{"pushed": "Push"},
+			err:     "events \"push\" and \"pushed\" would have the same method name Push",
+		},
+		"invalid method name": {
+			transitions: []fsm.Transition{
+				{FromState: "locked", Event: "2fa", ToState: "locked"},
+			},
+			err: "event \"2fa\" has no valid exported method name (use -method 2fa=Name)",
+		},
+		"unknown event": {
+			transitions: []fsm.Transition{
+				{FromState: "locked", Event: "push", ToState: "locked"},
+			},
+			methods: map[string]string{"coin": "InsertCoin"},
+			err:     "unknown event \"coin\" in method names",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			_, err := generate(fsm.Definition{Transitions: test.transitions}, config{
+				source:      "test.yaml",
+				packageName: "test",
+				stateType:   "State",
+				eventType:   "Event",
+				subjectType: "Subject",
+				field:       "stateMachine",
+				methods:     test.methods,
+			})
+
+			assert.EqualError(t, err, test.err)
+		})
+	}
+}
+
+func TestCamelCase(t *testing.T) {
+	tests := map[string]string{
+		"coin_inserted": "CoinInserted",
+		"in-fulfilment": "InFulfilment",
+		"order.shipped": "OrderShipped",
+		"Locked":        "Locked",
+		"retry 2 times": "Retry2Times",
+		"__leading__":   "Leading",
+	}
+
+	for name, expected := range tests {
+		assert.Equal(t, expected, camelCase(name), name)
+	}
+}
@@ -0,0 +1,118 @@
+// Command fsm-gen generates Go code from a state machine definition file.
+//
+// It generates state and event types with a constant for every state and event,
+// a state machine constructor and a method per event on a subject type, triggering the event for the subject.
+//
+// It is meant to be used with go generate:
+//
+//	//go:generate fsm-gen -type Turnstile -method coin_inserted=InsertCoin turnstile.yaml
+//
+// Definition files are YAML or JSON documents (see the definition package).
+//
+// Usage:
+//
+//	fsm-gen [flags] FILE
+//
+// Flags:
+//
+//	-o FILE             output file (default: FILE_fsm.go)
+//	-package NAME       package name (default: $GOPACKAGE)
+//	-state-type NAME    name of the state type (default: State)
+//	-event-type NAME    name of the event type (default: Event)
+//	-constructor NAME   name of the state machine constructor, empty to skip it (default: NewStateMachine)
+//	-type NAME          name of the subject type receiving a method per event
+//	-field NAME         field of the subject type holding the state machine (default: stateMachine)
+//	-method EVENT=NAME  name of the method generated for an event (default: the event name in camel case)
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/goph/fsm/definition"
+)
+
+// methodFlag collects EVENT=NAME method names.
+type methodFlag map[string]string
+
+func (f methodFlag) String() string {
+	return ""
+}
+
+func (f methodFlag) Set(value string) error {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return fmt.Errorf("expected EVENT=NAME")
+	}
+
+	f[parts[0]] = parts[1]
+
+	return nil
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stderr))
+}
+
+// run generates code and returns the exit code.
+func run(args []string, stderr io.Writer) int {
+	flags := flag.NewFlagSet("fsm-gen", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+
+	cfg := config{
+		methods: make(methodFlag),
+	}
+
+	output := flags.String("o", "", "output file (default: FILE_fsm.go)")
+	flags.StringVar(&cfg.packageName, "package", os.Getenv("GOPACKAGE"), "package name")
+	flags.StringVar(&cfg.stateType, "state-type", "State", "name of the state type")
+	flags.StringVar(&cfg.eventType, "event-type", "Event", "name of the event type")
+	flags.StringVar(&cfg.constructor, "constructor", "NewStateMachine", "name of the state machine constructor, empty to skip it")
+	flags.StringVar(&cfg.subjectType, "type", "", "name of the subject type receiving a method per event")
+	flags.StringVar(&cfg.field, "field", "stateMachine", "field of the subject type holding the state machine")
+	flags.Var(methodFlag(cfg.methods), "method", "name of the method generated for an event (EVENT=NAME)")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if flags.NArg() != 1 || cfg.packageName == "" {
+		fmt.Fprintln(stderr, "usage: fsm-gen [flags] FILE (-package is required outside of go generate)")
+
+		return 2
+	}
+
+	filename := flags.Arg(0)
+	cfg.source = filepath.Base(filename)
+
+	if *output == "" {
+		*output = strings.TrimSuffix(filename, filepath.Ext(filename)) + "_fsm.go"
+	}
+
+	def, err := definition.ReadFile(filename)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+
+		return 1
+	}
+
+	src, err := generate(def, cfg)
+	if err != nil {
+		fmt.Fprintf(stderr, "fsm-gen: %s\n", err)
+
+		return 1
+	}
+
+	if err := ioutil.WriteFile(*output, src, 0644); err != nil {
+		fmt.Fprintln(stderr, err)
+
+		return 1
+	}
+
+	return 0
+}
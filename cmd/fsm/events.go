@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+)
+
+// runListEvents lists the events accepted in a state, including the ones inherited from its parents.
+func runListEvents(args []string, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("list-events", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+
+	state := flags.String("state", "", "state to list the events of")
+
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	if flags.NArg() != 1 || *state == "" {
+		fmt.Fprintln(stderr, "usage: fsm list-events --state STATE FILE")
+
+		return exitUsage
+	}
+
+	def, err := load(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+
+		return exitFailure
+	}
+
+	if !containsState(def.StateNames(), *state) {
+		fmt.Fprintf(stderr, "fsm: unknown state %q\n", *state)
+
+		return exitFailure
+	}
+
+	// Every guard passes, so every event declared for the state (or its parents) is listed
+	for _, event := range newStateMachine(def).AvailableEvents(*state) {
+		fmt.Fprintln(stdout, event)
+	}
+
+	return exitOK
+}
+
+// containsState checks whether a state is in a list of states.
+func containsState(states []string, state string) bool {
+	for _, s := range states {
+		if s == state {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/goph/fsm/definition"
+	"github.com/goph/fsm/scxml"
+	"gopkg.in/yaml.v3"
+)
+
+// runFmt formats definition files in canonical form.
+func runFmt(args []string, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+
+	list := flags.Bool("l", false, "list files whose formatting differs from the canonical form")
+	write := flags.Bool("w", false, "write the result to the source file instead of the standard output")
+
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	if flags.NArg() == 0 {
+		fmt.Fprintln(stderr, "usage: fsm fmt [-l] [-w] FILE...")
+
+		return exitUsage
+	}
+
+	code := exitOK
+
+	for _, filename := range flags.Args() {
+		src, err := ioutil.ReadFile(filename)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			code = exitFailure
+
+			continue
+		}
+
+		formatted, kept, err := format(filename, src)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			code = exitFailure
+
+			continue
+		}
+
+		changed := !bytes.Equal(src, formatted)
+
+		if *list && changed {
+			fmt.Fprintln(stdout, filename)
+		}
+
+		if *write && changed {
+			// Files are never overwritten if comments would be lost
+			if !kept {
+				fmt.Fprintf(stderr, "%s: comments cannot be kept in canonical form, format the file manually\n", filename)
+				code = exitFailure
+
+				continue
+			}
+
+			if err := ioutil.WriteFile(filename, formatted, 0644); err != nil {
+				fmt.Fprintln(stderr, err)
+				code = exitFailure
+			}
+		}
+
+		if !*list && !*write {
+			stdout.Write(formatted)
+		}
+	}
+
+	return code
+}
+
+// format returns the canonical form of a definition file in its own format.
+//
+// Comments of YAML documents are kept as long as the commented fields are part of the canonical form.
+// It returns false if some of the comments cannot be kept.
+func format(filename string, src []byte) ([]byte, bool, error) {
+	def, err := load(filename)
+	if err != nil {
+		return nil, false, err
+	}
+
+	switch {
+	case isSCXML(filename):
+		var buf bytes.Buffer
+
+		err := scxml.Write(&buf, def)
+
+		return buf.Bytes(), !hasXMLComments(src), err
+
+	case strings.EqualFold(filepath.Ext(filename), ".json"):
+		formatted, err := definition.MarshalJSON(def)
+
+		return formatted, true, err
+	}
+
+	formatted, err := definition.Marshal(def)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return keepComments(src, formatted)
+}
+
+// keepComments copies the comments of a YAML document to its canonical form.
+//
+// It returns false if some of the comments cannot be kept.
+func keepComments(src []byte, formatted []byte) ([]byte, bool, error) {
+	var original, canonical yaml.Node
+
+	if err := yaml.Unmarshal(src, &original); err != nil {
+		return nil, false, err
+	}
+
+	if !hasComments(&original) {
+		return formatted, true, nil
+	}
+
+	if err := yaml.Unmarshal(formatted, &canonical); err != nil {
+		return nil, false, err
+	}
+
+	kept := copyComments(&original, &canonical)
+
+	var buf bytes.Buffer
+
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+
+	if err := encoder.Encode(&canonical); err != nil {
+		return nil, false, err
+	}
+
+	if err := encoder.Close(); err != nil {
+		return nil, false, err
+	}
+
+	return buf.Bytes(), kept, nil
+}
+
+// copyComments copies the comments of a node and its descendants to the corresponding nodes of an other tree.
+//
+// Mapping values correspond by their keys, sequence items by their index.
+// It returns false if some of the comments have no corresponding node.
+func copyComments(src *yaml.Node, dst *yaml.Node) bool {
+	dst.HeadComment = src.HeadComment
+	dst.LineComment = src.LineComment
+	dst.FootComment = src.FootComment
+
+	kept := true
+
+	switch {
+	case src.Kind == yaml.MappingNode && dst.Kind == yaml.MappingNode:
+		for i := 0; i+1 < len(src.Content); i += 2 {
+			j := mappingKey(dst, src.Content[i].Value)
+			if j < 0 {
+				kept = kept && !hasComments(src.Content[i]) && !hasComments(src.Content[i+1])
+
+				continue
+			}
+
+			kept = copyComments(src.Content[i], dst.Content[j]) && kept
+			kept = copyComments(src.Content[i+1], dst.Content[j+1]) && kept
+		}
+
+	case src.Kind == dst.Kind && len(src.Content) == len(dst.Content):
+		for i := range src.Content {
+			kept = copyComments(src.Content[i], dst.Content[i]) && kept
+		}
+
+	default:
+		for _, node := range src.Content {
+			kept = kept && !hasComments(node)
+		}
+	}
+
+	return kept
+}
+
+// mappingKey returns the index of a key in a mapping node or -1.
+func mappingKey(node *yaml.Node, key string) int {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// hasComments checks whether a node or any of its descendants has comments.
+func hasComments(node *yaml.Node) bool {
+	if node.HeadComment != "" || node.LineComment != "" || node.FootComment != "" {
+		return true
+	}
+
+	for _, child := range node.Content {
+		if hasComments(child) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hasXMLComments checks whether an XML document has comments.
+func hasXMLComments(src []byte) bool {
+	decoder := xml.NewDecoder(bytes.NewReader(src))
+
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return false
+		}
+
+		if _, ok := token.(xml.Comment); ok {
+			return true
+		}
+	}
+}
@@ -0,0 +1,128 @@
+// Command fsm validates, renders and formats state machine definition files.
+//
+// Definition files are YAML or JSON documents (see the definition package) or SCXML documents (with an .scxml extension).
+//
+// Usage:
+//
+//	fsm validate FILE...
+//	fsm render [--format dot|mermaid|plantuml] FILE
+//	fsm list-events --state STATE FILE
+//	fsm fmt [-l] [-w] FILE...
+//
+// Definitions are checked using the same rules as fsm.NewStateMachineStrict,
+// except for actions and guards which are bound to Go code.
+//
+// fmt keeps the comments of YAML files and never overwrites files whose comments would be lost.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/goph/fsm"
+	"github.com/goph/fsm/definition"
+	"github.com/goph/fsm/scxml"
+)
+
+// Exit codes
+const (
+	exitOK      = 0
+	exitFailure = 1
+	exitUsage   = 2
+)
+
+// command is a subcommand of the tool.
+type command struct {
+	name        string
+	usage       string
+	description string
+	run         func(args []string, stdout io.Writer, stderr io.Writer) int
+}
+
+var commands = []command{
+	{"validate", "validate FILE...", "Validate definition files", runValidate},
+	{"render", "render [--format dot|mermaid|plantuml] FILE", "Render a definition as a diagram", runRender},
+	{"list-events", "list-events --state STATE FILE", "List the events accepted in a state", runListEvents},
+	{"fmt", "fmt [-l] [-w] FILE...", "Format definition files in canonical form", runFmt},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run executes a subcommand and returns the exit code.
+func run(args []string, stdout io.Writer, stderr io.Writer) int {
+	if len(args) == 0 {
+		usage(stderr)
+
+		return exitUsage
+	}
+
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			return cmd.run(args[1:], stdout, stderr)
+		}
+	}
+
+	if args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		usage(stdout)
+
+		return exitOK
+	}
+
+	fmt.Fprintf(stderr, "fsm: unknown command %q\n", args[0])
+	usage(stderr)
+
+	return exitUsage
+}
+
+// usage prints the list of subcommands.
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage:")
+
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "\tfsm %-50s %s\n", cmd.usage, cmd.description)
+	}
+}
+
+// isSCXML checks whether a file is an SCXML document.
+func isSCXML(filename string) bool {
+	return strings.EqualFold(filepath.Ext(filename), ".scxml")
+}
+
+// load reads a definition file.
+func load(filename string) (fsm.Definition, error) {
+	if !isSCXML(filename) {
+		return definition.ReadFile(filename)
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		return fsm.Definition{}, err
+	}
+	defer file.Close()
+
+	def, err := scxml.Read(file)
+	if err != nil {
+		return fsm.Definition{}, fmt.Errorf("invalid definition %s: %s", filename, err)
+	}
+
+	return def, nil
+}
+
+// newStateMachine creates a state machine from a definition.
+//
+// Guards are bound to Go code, so every guard is accepted.
+func newStateMachine(def fsm.Definition) *fsm.StateMachine {
+	return fsm.NewStateMachine(nil, def.Transitions, append(def.Options(), fsm.WithGuard(anyGuard{}))...)
+}
+
+// anyGuard accepts every guard.
+type anyGuard struct{}
+
+func (anyGuard) Check(guard string, fromState string, toState string, args []interface{}) bool {
+	return true
+}
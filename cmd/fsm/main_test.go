@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	tests := map[string]struct {
+		args   []string
+		code   int
+		stdout string
+		stderr string
+	}{
+		"no command": {
+			args: nil,
+			code: exitUsage,
+		},
+		"unknown command": {
+			args: []string{"lint"},
+			code: exitUsage,
+		},
+		"validate": {
+			args: []string{"validate", "testdata/order.yaml"},
+			code: exitOK,
+		},
+		"validate invalid": {
+			args: []string{"validate", "testdata/invalid.yaml"},
+			code: exitFailure,
+			stdout: "testdata/invalid.yaml: transition 1 from \"new\" state triggered by \"pay\" event is shadowed by transition 0 (ambiguous_transition)\n" +
+				"testdata/invalid.yaml: state \"paid\" cannot be left, but it is not final (dead_end_state)\n" +
+				"testdata/invalid.yaml: state \"cancelled\" cannot be left, but it is not final (dead_end_state)\n",
+		},
+		"validate malformed": {
+			args:   []string{"validate", "testdata/malformed.yaml"},
+			code:   exitFailure,
+			stdout: "testdata/malformed.yaml:3:5: transition has no \"to\" field\n",
+		},
+		"render": {
+			args: []string{"render", "--format", "mermaid", "--state", "packing", "testdata/order.yaml"},
+			code: exitOK,
+			stdout: "stateDiagram-v2\n" +
+				"\t[*] --> new\n" +
+				"\tstate in_fulfilment {\n" +
+				"\t\t[*] --> picking\n" +
+				"\t\tpicking\n" +
+				"\t\tpacking\n" +
+				"\t\tpicking --> packing : pick\n" +
+				"\t}\n" +
+				"\tshipped\n" +
+				"\tshipped --> [*]\n" +
+				"\tcancelled\n" +
+				"\tcancelled --> [*]\n" +
+				"\tnew\n" +
+				"\tnew --> in_fulfilment : pay [payment_valid] / charge\n" +
+				"\tpacking --> shipped : pack\n" +
+				"\tin_fulfilment --> cancelled : cancel / refund\n" +
+				"\n" +
+				"\tclassDef current fill:lightblue,stroke-width:2px\n" +
+				"\tclass packing current\n",
+		},
+		"render unknown format": {
+			args:   []string{"render", "--format", "svg", "testdata/order.yaml"},
+			code:   exitUsage,
+			stderr: "fsm: unknown format \"svg\"\n",
+		},
+		"list events": {
+			args:   []string{"list-events", "--state", "picking", "testdata/order.yaml"},
+			code:   exitOK,
+			stdout: "pick\ncancel\n",
+		},
+		"list events of unknown state": {
+			args:   []string{"list-events", "--state", "pickign", "testdata/order.yaml"},
+			code:   exitFailure,
+			stderr: "fsm: unknown state \"pickign\"\n",
+		},
+		"list events without state": {
+			args:   []string{"list-events", "testdata/order.yaml"},
+			code:   exitUsage,
+			stderr: "usage: fsm list-events --state STATE FILE\n",
+		},
+		"fmt": {
+			args: []string{"fmt", "testdata/unformatted.json"},
+			code: exitOK,
+			stdout: "{\n" +
+				"\t\"initial\": \"new\",\n" +
+				"\t\"transitions\": [\n" +
+				"\t\t{\n" +
+				"\t\t\t\"from\": \"new\",\n" +
+				"\t\t\t\"event\": \"pay\",\n" +
+				"\t\t\t\"to\": \"paid\"\n" +
+				"\t\t}\n" +
+				"\t]\n" +
+				"}\n",
+		},
+		"fmt comments": {
+			args: []string{"fmt", "testdata/commented.yaml"},
+			code: exitOK,
+			stdout: "# Owned by payments team\n" +
+				"initial: new\n" +
+				"transitions:\n" +
+				"  # Payment is captured by the provider\n" +
+				"  - from: new\n" +
+				"    event: pay\n" +
+				"    to: paid # after capture\n",
+		},
+		"fmt list": {
+			args:   []string{"fmt", "-l", "testdata/order.yaml", "testdata/unformatted.json"},
+			code:   exitOK,
+			stdout: "testdata/unformatted.json\n",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+
+			code := run(test.args, &stdout, &stderr)
+
+			assert.Equal(t, test.code, code)
+			assert.Equal(t, test.stdout, stdout.String())
+
+			if test.stderr != "" {
+				assert.Equal(t, test.stderr, stderr.String())
+			}
+		})
+	}
+}
+
+func TestRun_FmtWrite_LostComments(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "order.yaml")
+	src := []byte("transitions:\n  - from: new\n    event: pay\n    to: paid\n    actions: [] # nothing to do yet\n")
+
+	require.NoError(t, ioutil.WriteFile(filename, src, 0644))
+
+	var stdout, stderr bytes.Buffer
+
+	code := run([]string{"fmt", "-w", filename}, &stdout, &stderr)
+
+	assert.Equal(t, exitFailure, code)
+	assert.Equal(t, filename+": comments cannot be kept in canonical form, format the file manually\n", stderr.String())
+
+	// The file is left untouched
+	data, err := ioutil.ReadFile(filename)
+	require.NoError(t, err)
+
+	assert.Equal(t, src, data)
+}
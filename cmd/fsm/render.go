@@ -0,0 +1,62 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/goph/fsm"
+	"github.com/goph/fsm/diagram"
+)
+
+var renderers = map[string]func(w io.Writer, def fsm.Definition, opts ...diagram.Option) error{
+	"dot":      diagram.WriteDOT,
+	"mermaid":  diagram.WriteMermaid,
+	"plantuml": diagram.WritePlantUML,
+}
+
+// runRender renders a definition file as a diagram.
+func runRender(args []string, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("render", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+
+	format := flags.String("format", "dot", "diagram format (dot, mermaid or plantuml)")
+	state := flags.String("state", "", "highlight a state as the current state")
+
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	if flags.NArg() != 1 {
+		fmt.Fprintln(stderr, "usage: fsm render [--format dot|mermaid|plantuml] FILE")
+
+		return exitUsage
+	}
+
+	render, ok := renderers[*format]
+	if !ok {
+		fmt.Fprintf(stderr, "fsm: unknown format %q\n", *format)
+
+		return exitUsage
+	}
+
+	def, err := load(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+
+		return exitFailure
+	}
+
+	var opts []diagram.Option
+	if *state != "" {
+		opts = append(opts, diagram.WithCurrentStates(*state))
+	}
+
+	if err := render(stdout, def, opts...); err != nil {
+		fmt.Fprintln(stderr, err)
+
+		return exitFailure
+	}
+
+	return exitOK
+}
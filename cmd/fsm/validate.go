@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/goph/fsm"
+	"github.com/goph/fsm/definition"
+)
+
+// runValidate validates definition files and prints every problem.
+func runValidate(args []string, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("validate", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	if flags.NArg() == 0 {
+		fmt.Fprintln(stderr, "usage: fsm validate FILE...")
+
+		return exitUsage
+	}
+
+	code := exitOK
+
+	for _, filename := range flags.Args() {
+		if !validate(filename, stdout) {
+			code = exitFailure
+		}
+	}
+
+	return code
+}
+
+// validate validates a definition file and reports whether it is valid.
+func validate(filename string, w io.Writer) bool {
+	def, err := load(filename)
+	if perr, ok := err.(*definition.ParseError); ok {
+		for _, problem := range perr.Problems() {
+			if problem.Line > 0 {
+				fmt.Fprintf(w, "%s:%d:%d: %s\n", filename, problem.Line, problem.Column, problem.Message)
+			} else {
+				fmt.Fprintf(w, "%s: %s\n", filename, problem.Message)
+			}
+		}
+
+		return false
+	} else if err != nil {
+		fmt.Fprintln(w, err)
+
+		return false
+	}
+
+	err = newStateMachine(def).Validate()
+	if verr, ok := err.(*fsm.ValidationError); ok {
+		for _, problem := range verr.Problems() {
+			fmt.Fprintf(w, "%s: %s (%s)\n", filename, problem.Message, problem.Kind)
+		}
+
+		return false
+	}
+
+	return true
+}
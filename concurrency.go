@@ -0,0 +1,131 @@
+package fsm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// VersionedSubject is a Subject whose state is committed by the state machine using optimistic concurrency control.
+//
+// The version is read before the transition and the new state is only committed if the version did not change meanwhile.
+// Since actions are executed before the commit, they should be part of the same (eg. database) transaction
+// or be safe to execute again.
+type VersionedSubject interface {
+	Subject
+
+	// GetVersion returns the version of the subject's current state.
+	GetVersion() int64
+
+	// CompareAndSwapState sets the state if the version still matches.
+	//
+	// It returns false if the version changed meanwhile.
+	CompareAndSwapState(version int64, state string) (bool, error)
+}
+
+// ConflictError is returned when the state of a VersionedSubject changed during a transition.
+type ConflictError struct {
+	*transitionError
+
+	nextState string
+	version   int64
+}
+
+// Code returns CodeConflict.
+func (e *ConflictError) Code() ErrorCode {
+	return CodeConflict
+}
+
+// Is matches ErrConflict.
+func (e *ConflictError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+// Error returns the formatted error message.
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf(
+		"state changed concurrently during transition from %q state triggered by %q event",
+		e.currentState,
+		e.event,
+	)
+}
+
+// NextState returns the state which could not be committed.
+func (e *ConflictError) NextState() string {
+	return e.nextState
+}
+
+// Version returns the version of the subject read before the transition.
+func (e *ConflictError) Version() int64 {
+	return e.version
+}
+
+// compareAndSwap commits the new state of a versioned subject.
+func (sm *StateMachine) compareAndSwap(
+	subject VersionedSubject,
+	version int64,
+	currentState string,
+	nextState string,
+	event string,
+	args []interface{},
+) error {
+	swapped, err := subject.CompareAndSwapState(version, nextState)
+	if err != nil {
+		return err
+	}
+
+	if !swapped {
+		return &ConflictError{
+			transitionError: &transitionError{
+				currentState: currentState,
+				event:        event,
+				args:         args,
+			},
+
+			nextState: nextState,
+			version:   version,
+		}
+	}
+
+	return nil
+}
+
+// SubjectLoader (re)loads a versioned subject, typically from a database.
+type SubjectLoader func(ctx context.Context) (VersionedSubject, error)
+
+// TriggerWithRetry loads a subject and triggers an event using its current state.
+//
+// When the state changes during the transition, the subject is loaded again and the event is triggered again
+// up to maxAttempts times in total. After that the last ConflictError is returned.
+// The event is triggered at least once, even if maxAttempts is less than one.
+func (sm *StateMachine) TriggerWithRetry(
+	ctx context.Context,
+	load SubjectLoader,
+	maxAttempts int,
+	event string,
+	args ...interface{},
+) error {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	var err error
+
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		var subject VersionedSubject
+
+		subject, err = load(ctx)
+		if err != nil {
+			return err
+		}
+
+		err = sm.TriggerSubjectContext(ctx, subject, event, args...)
+
+		var cerr *ConflictError
+		if !errors.As(err, &cerr) {
+			return err
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,293 @@
+package fsm_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/goph/fsm"
+	"github.com/goph/fsm/internal/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStateMachine_VersionedSubject(t *testing.T) {
+	delegate := new(mocks.Delegate)
+	transitions := []fsm.Transition{
+		{
+			FromState: "current_state",
+			Event:     "event",
+			ToState:   "next_state",
+			Action:    "action",
+		},
+	}
+
+	subject := new(mocks.VersionedSubject)
+
+	subject.On("GetState").Return("current_state")
+	subject.On("GetVersion").Return(int64(1))
+	subject.On("CompareAndSwapState", int64(1), "next_state").Return(true, nil)
+
+	delegate.On("Handle", "action", "current_state", "next_state", []interface{}{subject, "argument"}).Return(nil)
+
+	sm := fsm.NewStateMachine(delegate, transitions)
+
+	err := sm.TriggerSubject(subject, "event", "argument")
+
+	require.NoError(t, err)
+
+	delegate.AssertExpectations(t)
+	subject.AssertExpectations(t)
+}
+
+func TestStateMachine_VersionedSubject_Conflict(t *testing.T) {
+	delegate := new(mocks.Delegate)
+	transitions := []fsm.Transition{
+		{
+			FromState: "current_state",
+			Event:     "event",
+			ToState:   "next_state",
+			Action:    "action",
+		},
+	}
+
+	subject := new(mocks.VersionedSubject)
+
+	subject.On("GetState").Return("current_state")
+	subject.On("GetVersion").Return(int64(1))
+	subject.On("CompareAndSwapState", int64(1), "next_state").Return(false, nil)
+
+	delegate.On("Handle", "action", "current_state", "next_state", []interface{}{subject, "argument"}).Return(nil)
+
+	sm := fsm.NewStateMachine(delegate, transitions)
+
+	err := sm.TriggerSubject(subject, "event", "argument")
+
+	require.Error(t, err)
+
+	cerr := err.(*fsm.ConflictError)
+
+	assert.EqualError(t, cerr, "state changed concurrently during transition from \"current_state\" state triggered by \"event\" event")
+	assert.Equal(t, "current_state", cerr.CurrentState())
+	assert.Equal(t, "next_state", cerr.NextState())
+	assert.Equal(t, int64(1), cerr.Version())
+	assert.Equal(t, fsm.CodeConflict, cerr.Code())
+	assert.True(t, errors.Is(err, fsm.ErrConflict))
+}
+
+// versionedOrder is a subject persisting its state, version and history.
+type versionedOrder struct {
+	state   string
+	version int64
+	history fsm.History
+
+	// conflict makes every compare-and-swap fail
+	conflict bool
+}
+
+func (o *versionedOrder) GetState() string {
+	return o.state
+}
+
+func (o *versionedOrder) GetVersion() int64 {
+	return o.version
+}
+
+func (o *versionedOrder) CompareAndSwapState(version int64, state string) (bool, error) {
+	if o.conflict || version != o.version {
+		return false, nil
+	}
+
+	o.state = state
+	o.version++
+
+	return true, nil
+}
+
+func (o *versionedOrder) GetHistory() fsm.History {
+	return o.history
+}
+
+func (o *versionedOrder) SetHistory(history fsm.History) {
+	o.history = history
+}
+
+func TestStateMachine_VersionedSubject_ConflictHistory(t *testing.T) {
+	delegate := new(mocks.Delegate)
+	transitions := []fsm.Transition{
+		{
+			FromState: "a",
+			Event:     "hold",
+			ToState:   "on_hold",
+		},
+	}
+
+	states := []fsm.State{
+		{Name: "a", Initial: "a1"},
+		{Name: "a1", Parent: "a"},
+		{Name: "a.history", Parent: "a", History: fsm.ShallowHistory},
+	}
+
+	sm := fsm.NewStateMachine(delegate, transitions, fsm.WithStates(states))
+
+	subject := &versionedOrder{state: "a1", version: 1, conflict: true}
+
+	err := sm.TriggerSubject(subject, "hold")
+
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, fsm.ErrConflict))
+
+	// The history is only committed along with the state
+	assert.Nil(t, subject.history)
+
+	subject.conflict = false
+
+	err = sm.TriggerSubject(subject, "hold")
+
+	require.NoError(t, err)
+	assert.Equal(t, "on_hold", subject.state)
+	assert.Equal(t, fsm.History{"a": []string{"a1"}}, subject.history)
+}
+
+func TestStateMachine_VersionedSubject_DelegateError(t *testing.T) {
+	delegate := new(mocks.Delegate)
+	transitions := []fsm.Transition{
+		{
+			FromState: "current_state",
+			Event:     "event",
+			ToState:   "next_state",
+			Action:    "action",
+		},
+	}
+
+	subject := new(mocks.VersionedSubject)
+
+	subject.On("GetState").Return("current_state")
+	subject.On("GetVersion").Return(int64(1))
+
+	delegate.On("Handle", "action", "current_state", "next_state", []interface{}{subject, "argument"}).Return(errors.New("error happened"))
+
+	sm := fsm.NewStateMachine(delegate, transitions)
+
+	err := sm.TriggerSubject(subject, "event", "argument")
+
+	require.Error(t, err)
+	assert.IsType(t, &fsm.DelegateError{}, err)
+
+	subject.AssertNotCalled(t, "CompareAndSwapState", int64(1), "next_state")
+}
+
+func TestStateMachine_TriggerWithRetry(t *testing.T) {
+	delegate := new(mocks.Delegate)
+	transitions := []fsm.Transition{
+		{
+			FromState: "current_state",
+			Event:     "event",
+			ToState:   "next_state",
+			Action:    "action",
+		},
+	}
+
+	staleSubject := new(mocks.VersionedSubject)
+	staleSubject.On("GetState").Return("current_state")
+	staleSubject.On("GetVersion").Return(int64(1))
+	staleSubject.On("CompareAndSwapState", int64(1), "next_state").Return(false, nil)
+
+	freshSubject := new(mocks.VersionedSubject)
+	freshSubject.On("GetState").Return("current_state")
+	freshSubject.On("GetVersion").Return(int64(2))
+	freshSubject.On("CompareAndSwapState", int64(2), "next_state").Return(true, nil)
+
+	delegate.On("Handle", "action", "current_state", "next_state", []interface{}{staleSubject}).Return(nil)
+	delegate.On("Handle", "action", "current_state", "next_state", []interface{}{freshSubject}).Return(nil)
+
+	subjects := []fsm.VersionedSubject{staleSubject, freshSubject}
+
+	load := func(ctx context.Context) (fsm.VersionedSubject, error) {
+		subject := subjects[0]
+		subjects = subjects[1:]
+
+		return subject, nil
+	}
+
+	sm := fsm.NewStateMachine(delegate, transitions)
+
+	err := sm.TriggerWithRetry(context.Background(), load, 3, "event")
+
+	require.NoError(t, err)
+
+	staleSubject.AssertExpectations(t)
+	freshSubject.AssertExpectations(t)
+}
+
+func TestStateMachine_TriggerWithRetry_Exhausted(t *testing.T) {
+	delegate := new(mocks.Delegate)
+	transitions := []fsm.Transition{
+		{
+			FromState: "current_state",
+			Event:     "event",
+			ToState:   "next_state",
+			Action:    "action",
+		},
+	}
+
+	subject := new(mocks.VersionedSubject)
+	subject.On("GetState").Return("current_state")
+	subject.On("GetVersion").Return(int64(1))
+	subject.On("CompareAndSwapState", int64(1), "next_state").Return(false, nil)
+
+	delegate.On("Handle", "action", "current_state", "next_state", []interface{}{subject}).Return(nil)
+
+	var loads int
+
+	load := func(ctx context.Context) (fsm.VersionedSubject, error) {
+		loads++
+
+		return subject, nil
+	}
+
+	sm := fsm.NewStateMachine(delegate, transitions)
+
+	err := sm.TriggerWithRetry(context.Background(), load, 3, "event")
+
+	require.Error(t, err)
+	assert.IsType(t, &fsm.ConflictError{}, err)
+	assert.Equal(t, 3, loads)
+}
+
+func TestStateMachine_TriggerWithRetry_NoAttempts(t *testing.T) {
+	delegate := new(mocks.Delegate)
+	transitions := []fsm.Transition{
+		{
+			FromState: "current_state",
+			Event:     "event",
+			ToState:   "next_state",
+			Action:    "action",
+		},
+	}
+
+	subject := new(mocks.VersionedSubject)
+	subject.On("GetState").Return("current_state")
+	subject.On("GetVersion").Return(int64(1))
+	subject.On("CompareAndSwapState", int64(1), "next_state").Return(true, nil)
+
+	delegate.On("Handle", "action", "current_state", "next_state", []interface{}{subject}).Return(nil)
+
+	var loads int
+
+	load := func(ctx context.Context) (fsm.VersionedSubject, error) {
+		loads++
+
+		return subject, nil
+	}
+
+	sm := fsm.NewStateMachine(delegate, transitions)
+
+	// The event is triggered once at least
+	err := sm.TriggerWithRetry(context.Background(), load, 0, "event")
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, loads)
+
+	subject.AssertExpectations(t)
+}
@@ -0,0 +1,220 @@
+package fsm
+
+// Definition describes a state machine: its initial state, declared states and transitions.
+type Definition struct {
+	InitialState string
+	States       []State
+	Transitions  []Transition
+}
+
+// Definition returns a copy of the definition of the state machine.
+func (sm *StateMachine) Definition() Definition {
+	states := make([]State, len(sm.stateNames))
+	for i, name := range sm.stateNames {
+		states[i] = sm.states[name]
+	}
+
+	return Definition{
+		InitialState: sm.initialState,
+		States:       states,
+		Transitions:  append([]Transition(nil), sm.transitions...),
+	}
+}
+
+// StateNames returns every state of the definition:
+// first the declared states, then the initial state and the states referenced by transitions in order.
+//
+// AnyState is not a state, so it's not included.
+func (d Definition) StateNames() []string {
+	var names []string
+
+	seen := make(map[string]bool)
+
+	add := func(state string) {
+		if state != "" && !seen[state] {
+			seen[state] = true
+			names = append(names, state)
+		}
+	}
+
+	for _, state := range d.States {
+		add(state.Name)
+	}
+
+	add(d.InitialState)
+
+	for _, t := range d.Transitions {
+		if t.FromState != AnyState {
+			add(t.FromState)
+		}
+
+		for _, state := range t.FromStates {
+			add(state)
+		}
+
+		for _, state := range t.ExceptStates {
+			add(state)
+		}
+
+		add(t.ToState)
+	}
+
+	return names
+}
+
+// Sources returns the states of the definition a transition can be triggered in.
+//
+// For explicit transitions these are the source states.
+// For wildcard transitions these are the outermost states which are not excluded and don't contain excluded states:
+// just like transitions declared on a parent state, the transition can be triggered in their descendants as well.
+// States which are not part of the definition are not included, even though wildcard transitions apply to them.
+func (d Definition) Sources(t Transition) []string {
+	if t.FromState != AnyState {
+		return sources(&t)
+	}
+
+	names := d.StateNames()
+	h := newHierarchy(d.States)
+
+	// clean checks whether a state is not excluded and contains no excluded states
+	clean := func(state string) bool {
+		for _, except := range t.ExceptStates {
+			if h.within(state, except) || h.within(except, state) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	var states []string
+
+	for _, name := range names {
+		if d.State(name).History != "" || !clean(name) {
+			continue
+		}
+
+		if parent := h.states[name].Parent; parent == "" || !clean(parent) {
+			states = append(states, name)
+		}
+	}
+
+	return states
+}
+
+// Preempted checks whether a transition can never be triggered in a state (or one of its descendants),
+// because an unguarded transition triggered by the same event takes precedence over it.
+//
+// Just like when triggering an event, explicit transitions take precedence over wildcard transitions,
+// transitions of inner states take precedence over transitions of their ancestors
+// and transitions declared earlier take precedence over later ones.
+func (d Definition) Preempted(i int, state string) bool {
+	t := &d.Transitions[i]
+	h := newHierarchy(d.States)
+
+	for _, s := range h.ancestors(state) {
+		for j := range d.Transitions {
+			other := &d.Transitions[j]
+			if other.Event != t.Event || other.FromState == AnyState || !hasSource(other, s) {
+				continue
+			}
+
+			if j == i {
+				return false
+			}
+
+			if other.Guard == "" {
+				return true
+			}
+		}
+	}
+
+	for j := range d.Transitions {
+		other := &d.Transitions[j]
+		if other.Event != t.Event || other.FromState != AnyState || h.excludes(other, state) {
+			continue
+		}
+
+		if j == i {
+			return false
+		}
+
+		if other.Guard == "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// State returns a declared state.
+//
+// States which are not declared are returned with their name only.
+func (d Definition) State(name string) State {
+	for _, state := range d.States {
+		if state.Name == name {
+			return state
+		}
+	}
+
+	return State{Name: name}
+}
+
+// Ancestors returns the ancestors of a state from the innermost to the outermost one.
+func (d Definition) Ancestors(state string) []string {
+	h := newHierarchy(d.States)
+
+	return h.ancestors(state)[1:]
+}
+
+// IsAncestor checks whether a state is a proper ancestor of another one.
+func (d Definition) IsAncestor(ancestor string, state string) bool {
+	h := newHierarchy(d.States)
+
+	return h.isAncestor(ancestor, state)
+}
+
+// Children returns the child states of a composite state in declaration order.
+//
+// History pseudo-states are not included.
+func (d Definition) Children(state string) []string {
+	h := newHierarchy(d.States)
+
+	return h.children[state]
+}
+
+// Targets returns the leaf states entered when a transition targets a state (without recorded history).
+//
+// Targeting a parallel state enters every region, so it results in multiple leaf states.
+// Targeting a history pseudo-state enters its default state or, without a default state, its parent.
+func (d Definition) Targets(state string) []string {
+	h := newHierarchy(d.States)
+
+	return h.resolveTargets(state, nil)
+}
+
+// Domain returns the innermost state which is a proper ancestor of both the source and the target state of a transition.
+//
+// An empty string means that the transition crosses the top level states.
+func (d Definition) Domain(source string, target string) string {
+	h := newHierarchy(d.States)
+
+	return h.transitionDomain(source, target)
+}
+
+// Options returns the options declaring the states and the initial state of the definition.
+//
+//	sm := fsm.NewStateMachine(delegate, def.Transitions, def.Options()...)
+func (d Definition) Options() []Option {
+	var opts []Option
+
+	if d.InitialState != "" {
+		opts = append(opts, WithInitialState(d.InitialState))
+	}
+
+	if len(d.States) > 0 {
+		opts = append(opts, WithStates(d.States))
+	}
+
+	return opts
+}
@@ -0,0 +1,104 @@
+// Package definition loads state machine definitions from YAML and JSON documents.
+//
+// A definition declares the initial state, the states and the transitions of a state machine:
+//
+//	initial: locked
+//	states:
+//	  - name: broken
+//	    final: true
+//	transitions:
+//	  - from: locked
+//	    event: insert_coin
+//	    to: unlocked
+//	    action: unlock
+//	    guard: valid_coin
+//	    metadata:
+//	      owner: payments
+//	  - from: unlocked
+//	    event: push
+//	    to: locked
+//
+// Transitions support the from, except, event, to, action, actions (a list of actions executed after action),
+// guard and metadata fields, matching fsm.Transition.
+// The from field is either a single state ("*" meaning any state) or a list of states:
+//
+//	transitions:
+//	  - from: "*"
+//	    except: [shipped, cancelled]
+//	    event: cancel
+//	    to: cancelled
+//	  - from: [picking, packing]
+//	    event: hold
+//	    to: on_hold
+//
+// States support the name, parent, initial, parallel, history (shallow or deep),
+// final, on_enter and on_exit fields, matching fsm.State.
+//
+// Since JSON is a subset of YAML, the same format can be written in JSON as well.
+//
+// Actions (and guards) are only referenced by name,
+// they are bound to Go code by the delegate (and the guard) of the state machine:
+//
+//	def, err := definition.ReadFile("turnstile.yaml")
+//	if err != nil {
+//		// handle error
+//	}
+//
+//	sm := fsm.NewStateMachine(delegate, def.Transitions, def.Options()...)
+package definition
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Problem describes a single problem of a malformed definition document.
+type Problem struct {
+	// Line is the line (starting from 1) the problem is located at or 0 if it's unknown.
+	Line int
+
+	// Column is the column (starting from 1) the problem is located at or 0 if it's unknown.
+	Column int
+
+	Message string
+}
+
+// String returns the problem message prefixed with its position.
+func (p Problem) String() string {
+	switch {
+	case p.Line > 0 && p.Column > 0:
+		return fmt.Sprintf("line %d, column %d: %s", p.Line, p.Column, p.Message)
+
+	case p.Line > 0:
+		return fmt.Sprintf("line %d: %s", p.Line, p.Message)
+	}
+
+	return p.Message
+}
+
+// ParseError is returned when a definition document is malformed.
+type ParseError struct {
+	// Filename is the name of the file the definition is read from if any.
+	Filename string
+
+	problems []Problem
+}
+
+// Problems returns every problem found in the document.
+func (e *ParseError) Problems() []Problem {
+	return e.problems
+}
+
+// Error returns the formatted error message.
+func (e *ParseError) Error() string {
+	messages := make([]string, len(e.problems))
+	for i, problem := range e.problems {
+		messages[i] = problem.String()
+	}
+
+	if e.Filename != "" {
+		return fmt.Sprintf("invalid definition %s: %s", e.Filename, strings.Join(messages, "; "))
+	}
+
+	return fmt.Sprintf("invalid definition: %s", strings.Join(messages, "; "))
+}
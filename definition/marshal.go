@@ -0,0 +1,120 @@
+package definition
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/goph/fsm"
+	"gopkg.in/yaml.v3"
+)
+
+// document is the serialized form of a definition.
+type document struct {
+	Initial     string       `yaml:"initial,omitempty" json:"initial,omitempty"`
+	States      []state      `yaml:"states,omitempty" json:"states,omitempty"`
+	Transitions []transition `yaml:"transitions,omitempty" json:"transitions,omitempty"`
+}
+
+type state struct {
+	Name     string          `yaml:"name" json:"name"`
+	Parent   string          `yaml:"parent,omitempty" json:"parent,omitempty"`
+	Initial  string          `yaml:"initial,omitempty" json:"initial,omitempty"`
+	Parallel bool            `yaml:"parallel,omitempty" json:"parallel,omitempty"`
+	History  fsm.HistoryType `yaml:"history,omitempty" json:"history,omitempty"`
+	Final    bool            `yaml:"final,omitempty" json:"final,omitempty"`
+	OnEnter  string          `yaml:"on_enter,omitempty" json:"on_enter,omitempty"`
+	OnExit   string          `yaml:"on_exit,omitempty" json:"on_exit,omitempty"`
+}
+
+type transition struct {
+	// From is a single state or a list of states
+	From     interface{}       `yaml:"from" json:"from"`
+	Except   []string          `yaml:"except,omitempty" json:"except,omitempty"`
+	Event    string            `yaml:"event" json:"event"`
+	To       string            `yaml:"to" json:"to"`
+	Action   string            `yaml:"action,omitempty" json:"action,omitempty"`
+	Actions  []string          `yaml:"actions,omitempty" json:"actions,omitempty"`
+	Guard    string            `yaml:"guard,omitempty" json:"guard,omitempty"`
+	Metadata map[string]string `yaml:"metadata,omitempty" json:"metadata,omitempty"`
+}
+
+// newDocument converts a definition to its serialized form.
+func newDocument(def fsm.Definition) document {
+	doc := document{
+		Initial: def.InitialState,
+	}
+
+	for _, s := range def.States {
+		doc.States = append(doc.States, state{
+			Name:     s.Name,
+			Parent:   s.Parent,
+			Initial:  s.Initial,
+			Parallel: s.Parallel,
+			History:  s.History,
+			Final:    s.Final,
+			OnEnter:  s.OnEnter,
+			OnExit:   s.OnExit,
+		})
+	}
+
+	for _, t := range def.Transitions {
+		doc.Transitions = append(doc.Transitions, transition{
+			From:     from(t),
+			Except:   t.ExceptStates,
+			Event:    t.Event,
+			To:       t.ToState,
+			Action:   t.Action,
+			Actions:  t.Actions,
+			Guard:    t.Guard,
+			Metadata: t.Metadata,
+		})
+	}
+
+	return doc
+}
+
+// from returns the serialized source states of a transition.
+func from(t fsm.Transition) interface{} {
+	if len(t.FromStates) == 0 {
+		return t.FromState
+	}
+
+	if t.FromState == "" {
+		return t.FromStates
+	}
+
+	return append([]string{t.FromState}, t.FromStates...)
+}
+
+// Marshal serializes a definition as a YAML document in canonical form.
+//
+// Fields are written in a fixed order and empty fields are omitted.
+// States and transitions keep their order, since it's meaningful.
+func Marshal(def fsm.Definition) ([]byte, error) {
+	var buf bytes.Buffer
+
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+
+	if err := encoder.Encode(newDocument(def)); err != nil {
+		return nil, err
+	}
+
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// MarshalJSON serializes a definition as a JSON document in canonical form.
+//
+// See Marshal for details.
+func MarshalJSON(def fsm.Definition) ([]byte, error) {
+	data, err := json.MarshalIndent(newDocument(def), "", "\t")
+	if err != nil {
+		return nil, err
+	}
+
+	return append(data, '\n'), nil
+}
@@ -0,0 +1,40 @@
+package definition_test
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/goph/fsm/definition"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarshal(t *testing.T) {
+	data, err := definition.Marshal(turnstile())
+	require.NoError(t, err)
+
+	expected, err := ioutil.ReadFile("testdata/turnstile.canonical.yaml")
+	require.NoError(t, err)
+
+	assert.Equal(t, string(expected), string(data))
+
+	def, err := definition.Parse(data)
+	require.NoError(t, err)
+
+	assert.Equal(t, turnstile(), def)
+}
+
+func TestMarshalJSON(t *testing.T) {
+	data, err := definition.MarshalJSON(turnstile())
+	require.NoError(t, err)
+
+	expected, err := ioutil.ReadFile("testdata/turnstile.json")
+	require.NoError(t, err)
+
+	assert.Equal(t, string(expected), string(data))
+
+	def, err := definition.Parse(data)
+	require.NoError(t, err)
+
+	assert.Equal(t, turnstile(), def)
+}
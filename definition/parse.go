@@ -0,0 +1,309 @@
+package definition
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/goph/fsm"
+	"gopkg.in/yaml.v3"
+)
+
+// Parse parses a YAML or JSON definition document.
+//
+// Every problem of a malformed document is reported at once in a ParseError.
+func Parse(data []byte) (fsm.Definition, error) {
+	var document yaml.Node
+
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return fsm.Definition{}, &ParseError{problems: []Problem{syntaxProblem(err)}}
+	}
+
+	p := new(parser)
+
+	def := p.parseDocument(&document)
+
+	if len(p.problems) > 0 {
+		return fsm.Definition{}, &ParseError{problems: p.problems}
+	}
+
+	return def, nil
+}
+
+// Read reads and parses a YAML or JSON definition document.
+func Read(r io.Reader) (fsm.Definition, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return fsm.Definition{}, err
+	}
+
+	return Parse(data)
+}
+
+// ReadFile reads and parses a YAML or JSON definition file.
+func ReadFile(filename string) (fsm.Definition, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return fsm.Definition{}, err
+	}
+
+	def, err := Parse(data)
+	if err, ok := err.(*ParseError); ok {
+		err.Filename = filename
+	}
+
+	return def, err
+}
+
+var syntaxErrorPattern = regexp.MustCompile(`^yaml: line (\d+): (.*)$`)
+
+// syntaxProblem converts a YAML syntax error to a problem.
+func syntaxProblem(err error) Problem {
+	if matches := syntaxErrorPattern.FindStringSubmatch(err.Error()); matches != nil {
+		line, _ := strconv.Atoi(matches[1])
+
+		return Problem{Line: line, Message: matches[2]}
+	}
+
+	return Problem{Message: strings.TrimPrefix(err.Error(), "yaml: ")}
+}
+
+// parser collects the problems of a document while converting it to a definition.
+type parser struct {
+	problems []Problem
+}
+
+// errorf records a problem located at a node.
+func (p *parser) errorf(node *yaml.Node, format string, args ...interface{}) {
+	p.problems = append(p.problems, Problem{
+		Line:    node.Line,
+		Column:  node.Column,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (p *parser) parseDocument(document *yaml.Node) fsm.Definition {
+	var def fsm.Definition
+
+	if document.Kind != yaml.DocumentNode || len(document.Content) == 0 {
+		p.problems = append(p.problems, Problem{Line: 1, Message: "document is empty"})
+
+		return def
+	}
+
+	p.mapping(document.Content[0], "definition", nil, map[string]func(node *yaml.Node){
+		"initial": func(node *yaml.Node) {
+			def.InitialState = p.str(node)
+		},
+		"states": func(node *yaml.Node) {
+			p.sequence(node, func(node *yaml.Node) {
+				def.States = append(def.States, p.parseState(node))
+			})
+		},
+		"transitions": func(node *yaml.Node) {
+			p.sequence(node, func(node *yaml.Node) {
+				def.Transitions = append(def.Transitions, p.parseTransition(node))
+			})
+		},
+	})
+
+	return def
+}
+
+func (p *parser) parseState(node *yaml.Node) fsm.State {
+	var state fsm.State
+
+	p.mapping(node, "state", []string{"name"}, map[string]func(node *yaml.Node){
+		"name": func(node *yaml.Node) {
+			state.Name = p.str(node)
+		},
+		"parent": func(node *yaml.Node) {
+			state.Parent = p.str(node)
+		},
+		"initial": func(node *yaml.Node) {
+			state.Initial = p.str(node)
+		},
+		"parallel": func(node *yaml.Node) {
+			state.Parallel = p.boolean(node)
+		},
+		"history": func(node *yaml.Node) {
+			state.History = fsm.HistoryType(p.str(node))
+
+			if state.History != "" && state.History != fsm.ShallowHistory && state.History != fsm.DeepHistory {
+				p.errorf(node, "unknown history type %q (expected %q or %q)", state.History, fsm.ShallowHistory, fsm.DeepHistory)
+			}
+		},
+		"final": func(node *yaml.Node) {
+			state.Final = p.boolean(node)
+		},
+		"on_enter": func(node *yaml.Node) {
+			state.OnEnter = p.str(node)
+		},
+		"on_exit": func(node *yaml.Node) {
+			state.OnExit = p.str(node)
+		},
+	})
+
+	return state
+}
+
+func (p *parser) parseTransition(node *yaml.Node) fsm.Transition {
+	var transition fsm.Transition
+
+	p.mapping(node, "transition", []string{"from", "event", "to"}, map[string]func(node *yaml.Node){
+		"from": func(node *yaml.Node) {
+			if node.Kind != yaml.SequenceNode {
+				transition.FromState = p.str(node)
+
+				return
+			}
+
+			p.sequence(node, func(node *yaml.Node) {
+				transition.FromStates = append(transition.FromStates, p.str(node))
+			})
+		},
+		"except": func(node *yaml.Node) {
+			p.sequence(node, func(node *yaml.Node) {
+				transition.ExceptStates = append(transition.ExceptStates, p.str(node))
+			})
+		},
+		"event": func(node *yaml.Node) {
+			transition.Event = p.str(node)
+		},
+		"to": func(node *yaml.Node) {
+			transition.ToState = p.str(node)
+		},
+		"action": func(node *yaml.Node) {
+			transition.Action = p.str(node)
+		},
+		"actions": func(node *yaml.Node) {
+			p.sequence(node, func(node *yaml.Node) {
+				transition.Actions = append(transition.Actions, p.str(node))
+			})
+		},
+		"guard": func(node *yaml.Node) {
+			transition.Guard = p.str(node)
+		},
+		"metadata": func(node *yaml.Node) {
+			transition.Metadata = p.stringMap(node)
+		},
+	})
+
+	return transition
+}
+
+// mapping parses a mapping node by calling the parser of each field.
+//
+// Unknown, duplicate and missing required fields are reported.
+func (p *parser) mapping(node *yaml.Node, name string, required []string, fields map[string]func(node *yaml.Node)) {
+	if node.Kind != yaml.MappingNode {
+		p.errorf(node, "%s must be a mapping", name)
+
+		return
+	}
+
+	seen := make(map[string]bool)
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+
+		field, ok := fields[key.Value]
+
+		switch {
+		case !ok:
+			p.errorf(key, "unknown %s field %q", name, key.Value)
+
+		case seen[key.Value]:
+			p.errorf(key, "duplicate %s field %q", name, key.Value)
+
+		default:
+			seen[key.Value] = true
+
+			field(value)
+		}
+	}
+
+	for _, field := range required {
+		if !seen[field] {
+			p.errorf(node, "%s has no %q field", name, field)
+		}
+	}
+}
+
+// sequence parses a sequence node by calling a function with each item.
+func (p *parser) sequence(node *yaml.Node, item func(node *yaml.Node)) {
+	// An empty value is an empty list
+	if node.Tag == "!!null" {
+		return
+	}
+
+	if node.Kind != yaml.SequenceNode {
+		p.errorf(node, "expected a list")
+
+		return
+	}
+
+	for _, node := range node.Content {
+		item(node)
+	}
+}
+
+// str parses a scalar node as a string.
+func (p *parser) str(node *yaml.Node) string {
+	if node.Kind != yaml.ScalarNode {
+		p.errorf(node, "expected a string")
+
+		return ""
+	}
+
+	if node.Tag == "!!null" {
+		return ""
+	}
+
+	return node.Value
+}
+
+// boolean parses a scalar node as a boolean.
+func (p *parser) boolean(node *yaml.Node) bool {
+	var value bool
+
+	if node.Kind != yaml.ScalarNode || node.Tag != "!!bool" || node.Decode(&value) != nil {
+		p.errorf(node, "expected a boolean")
+
+		return false
+	}
+
+	return value
+}
+
+// stringMap parses a mapping node with scalar values.
+func (p *parser) stringMap(node *yaml.Node) map[string]string {
+	if node.Tag == "!!null" {
+		return nil
+	}
+
+	if node.Kind != yaml.MappingNode {
+		p.errorf(node, "expected a mapping")
+
+		return nil
+	}
+
+	values := make(map[string]string, len(node.Content)/2)
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+
+		if _, ok := values[key.Value]; ok {
+			p.errorf(key, "duplicate key %q", key.Value)
+
+			continue
+		}
+
+		values[key.Value] = p.str(value)
+	}
+
+	return values
+}
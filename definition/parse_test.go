@@ -0,0 +1,168 @@
+package definition_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/goph/fsm"
+	"github.com/goph/fsm/definition"
+	"github.com/goph/fsm/internal/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func turnstile() fsm.Definition {
+	return fsm.Definition{
+		InitialState: "locked",
+		States: []fsm.State{
+			{Name: "broken", Final: true, OnEnter: "call_maintenance"},
+		},
+		Transitions: []fsm.Transition{
+			{
+				FromState: "locked",
+				Event:     "insert_coin",
+				ToState:   "unlocked",
+				Action:    "unlock",
+				Guard:     "valid_coin",
+				Metadata:  map[string]string{"owner": "payments"},
+			},
+			{FromState: "unlocked", Event: "push", ToState: "locked", Action: "lock"},
+			{FromState: "unlocked", Event: "break", ToState: "broken", Actions: []string{"lock", "alert_staff"}},
+			{FromStates: []string{"locked", "unlocked"}, Event: "inspect", ToState: "locked"},
+			{FromState: fsm.AnyState, ExceptStates: []string{"broken"}, Event: "power_off", ToState: "broken"},
+		},
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	tests := map[string]string{
+		"yaml": "testdata/turnstile.yaml",
+		"json": "testdata/turnstile.json",
+	}
+
+	for name, filename := range tests {
+		filename := filename
+
+		t.Run(name, func(t *testing.T) {
+			def, err := definition.ReadFile(filename)
+			require.NoError(t, err)
+
+			assert.Equal(t, turnstile(), def)
+		})
+	}
+}
+
+func TestReadFile_Invalid(t *testing.T) {
+	_, err := definition.ReadFile("testdata/invalid.yaml")
+	require.Error(t, err)
+
+	perr, ok := err.(*definition.ParseError)
+	require.True(t, ok)
+
+	assert.Equal(t, "testdata/invalid.yaml", perr.Filename)
+	assert.Equal(
+		t,
+		[]definition.Problem{
+			{Line: 5, Column: 12, Message: "expected a boolean"},
+			{Line: 8, Column: 5, Message: "transition has no \"to\" field"},
+			{Line: 13, Column: 5, Message: "unknown transition field \"actoin\""},
+		},
+		perr.Problems(),
+	)
+	assert.EqualError(
+		t,
+		err,
+		"invalid definition testdata/invalid.yaml: "+
+			"line 5, column 12: expected a boolean; "+
+			"line 8, column 5: transition has no \"to\" field; "+
+			"line 13, column 5: unknown transition field \"actoin\"",
+	)
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := map[string]struct {
+		document string
+		problems []definition.Problem
+	}{
+		"syntax error": {
+			document: "initial: locked\ntransitions: [\n",
+			problems: []definition.Problem{
+				{Line: 2, Message: "did not find expected node content"},
+			},
+		},
+		"empty document": {
+			document: "",
+			problems: []definition.Problem{
+				{Line: 1, Message: "document is empty"},
+			},
+		},
+		"not a mapping": {
+			document: "- locked\n",
+			problems: []definition.Problem{
+				{Line: 1, Column: 1, Message: "definition must be a mapping"},
+			},
+		},
+		"duplicate field": {
+			document: "initial: locked\ninitial: unlocked\n",
+			problems: []definition.Problem{
+				{Line: 2, Column: 1, Message: "duplicate definition field \"initial\""},
+			},
+		},
+		"unknown history": {
+			document: "states:\n  - name: h\n    history: shallower\n",
+			problems: []definition.Problem{
+				{Line: 3, Column: 14, Message: "unknown history type \"shallower\" (expected \"shallow\" or \"deep\")"},
+			},
+		},
+		"transitions not a list": {
+			document: "transitions: locked\n",
+			problems: []definition.Problem{
+				{Line: 1, Column: 14, Message: "expected a list"},
+			},
+		},
+		"actions not a list": {
+			document: "transitions:\n  - {from: locked, event: push, to: locked, actions: lock}\n",
+			problems: []definition.Problem{
+				{Line: 2, Column: 54, Message: "expected a list"},
+			},
+		},
+		"json": {
+			document: "{\"transitions\": [{\"from\": {\"state\": \"locked\"}, \"event\": \"push\", \"to\": \"locked\"}]}",
+			problems: []definition.Problem{
+				{Line: 1, Column: 27, Message: "expected a string"},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			_, err := definition.Parse([]byte(test.document))
+			require.Error(t, err)
+
+			perr, ok := err.(*definition.ParseError)
+			require.True(t, ok)
+
+			assert.Equal(t, test.problems, perr.Problems())
+		})
+	}
+}
+
+func TestRead_StateMachine(t *testing.T) {
+	def, err := definition.Read(bytes.NewBufferString("initial: locked\ntransitions:\n  - {from: locked, event: coin, to: unlocked, action: unlock}\n  - {from: unlocked, event: push, to: locked}\n"))
+	require.NoError(t, err)
+
+	delegate := new(mocks.Delegate)
+	delegate.On("Handle", "unlock", "locked", "unlocked", []interface{}(nil)).Return(nil)
+
+	actions := fsm.NewActionMuxDelegate(map[string]fsm.Delegate{"unlock": delegate})
+
+	sm, err := fsm.NewStateMachineStrict(actions, def.Transitions, def.Options()...)
+	require.NoError(t, err)
+
+	err = sm.Trigger("locked", "coin")
+	require.NoError(t, err)
+
+	delegate.AssertExpectations(t)
+}
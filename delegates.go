@@ -1,5 +1,13 @@
 package fsm
 
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
 // ActionMuxDelegate allows to register a set of delegates per action.
 type ActionMuxDelegate struct {
 	delegates map[string]Delegate
@@ -12,13 +20,32 @@ func NewActionMuxDelegate(delegates map[string]Delegate) *ActionMuxDelegate {
 
 // Handle calls the underlying delegate for an action if any.
 func (d *ActionMuxDelegate) Handle(action string, fromState string, toState string, args []interface{}) error {
+	return d.HandleContext(context.Background(), action, fromState, toState, args)
+}
+
+// HandleContext calls the underlying delegate for an action if any.
+//
+// The context is passed to context-aware delegates.
+func (d *ActionMuxDelegate) HandleContext(ctx context.Context, action string, fromState string, toState string, args []interface{}) error {
 	if delegate, ok := d.delegates[action]; ok {
-		return delegate.Handle(action, fromState, toState, args)
+		return handleContext(ctx, delegate, action, fromState, toState, args)
 	}
 
 	return nil
 }
 
+// Actions returns the actions handled by the delegate in alphabetical order.
+func (d *ActionMuxDelegate) Actions() []string {
+	actions := make([]string, 0, len(d.delegates))
+	for action := range d.delegates {
+		actions = append(actions, action)
+	}
+
+	sort.Strings(actions)
+
+	return actions
+}
+
 func (d *ActionMuxDelegate) SetStateMachine(sm *StateMachine) {
 	for _, delegate := range d.delegates {
 		if smaDelegate, ok := delegate.(StateMachineAwareDelegate); ok {
@@ -27,25 +54,136 @@ func (d *ActionMuxDelegate) SetStateMachine(sm *StateMachine) {
 	}
 }
 
+// ErrorPolicy decides how a CompositeDelegate handles errors returned by its delegates.
+type ErrorPolicy string
+
+const (
+	// BestEffort calls every delegate and ignores their errors.
+	BestEffort ErrorPolicy = "best_effort"
+
+	// FailFast stops at the first error and returns it in a ChildDelegateError.
+	FailFast ErrorPolicy = "fail_fast"
+
+	// Aggregate calls every delegate and returns their errors in a CompositeError.
+	Aggregate ErrorPolicy = "aggregate"
+)
+
+// ChildDelegateError wraps an error returned by a delegate of a CompositeDelegate.
+type ChildDelegateError struct {
+	index    int
+	delegate Delegate
+	err      error
+}
+
+// Index returns the position of the failing delegate in the CompositeDelegate.
+func (e *ChildDelegateError) Index() int {
+	return e.index
+}
+
+// Delegate returns the failing delegate.
+func (e *ChildDelegateError) Delegate() Delegate {
+	return e.delegate
+}
+
+// Unwrap returns the error returned by the delegate.
+func (e *ChildDelegateError) Unwrap() error {
+	return e.err
+}
+
+// Error returns the formatted error message.
+func (e *ChildDelegateError) Error() string {
+	return fmt.Sprintf("delegate %d (%T): %s", e.index, e.delegate, e.err.Error())
+}
+
+// CompositeError is returned by a CompositeDelegate with the Aggregate policy when delegates return errors.
+type CompositeError struct {
+	errs []error
+}
+
+// Errors returns the errors of the failing delegates (each of them is a ChildDelegateError).
+func (e *CompositeError) Errors() []error {
+	return e.errs
+}
+
+// Unwrap returns the errors of the failing delegates, so that errors.Is and errors.As can inspect them.
+func (e *CompositeError) Unwrap() []error {
+	return e.errs
+}
+
+// Error returns the formatted error message.
+func (e *CompositeError) Error() string {
+	messages := make([]string, len(e.errs))
+	for i, err := range e.errs {
+		messages[i] = err.Error()
+	}
+
+	return fmt.Sprintf("%d delegates reported errors: %s", len(e.errs), strings.Join(messages, "; "))
+}
+
 // CompositeDelegate allows to multiplex the single delegate in the state machine.
 type CompositeDelegate struct {
 	delegates []Delegate
+	policy    ErrorPolicy
 }
 
-// NewCompositeDelegate returns a new CompositeDelegate.
+// NewCompositeDelegate returns a new CompositeDelegate with the BestEffort policy.
 func NewCompositeDelegate(delegates []Delegate) *CompositeDelegate {
-	return &CompositeDelegate{delegates}
+	return NewCompositeDelegateWithPolicy(delegates, BestEffort)
+}
+
+// NewCompositeDelegateWithPolicy returns a new CompositeDelegate with an error policy.
+//
+// It panics if the policy is unknown, so that a mistyped policy doesn't swallow errors.
+func NewCompositeDelegateWithPolicy(delegates []Delegate, policy ErrorPolicy) *CompositeDelegate {
+	switch policy {
+	case BestEffort, FailFast, Aggregate:
+	default:
+		panic(fmt.Sprintf("unknown error policy %q", policy))
+	}
+
+	return &CompositeDelegate{delegates, policy}
 }
 
 // Handle calls the underlying delegates.
 func (d *CompositeDelegate) Handle(action string, fromState string, toState string, args []interface{}) error {
-	for _, delegate := range d.delegates {
-		// TODO: consider collecting and returning errors
-		err := delegate.Handle(action, fromState, toState, args)
-		if err == StopPropagation {
+	return d.HandleContext(context.Background(), action, fromState, toState, args)
+}
+
+// HandleContext calls the underlying delegates.
+//
+// The context is passed to context-aware delegates.
+//
+// StopPropagation stops calling further delegates regardless of the policy and it is returned,
+// unless errors were already collected by the Aggregate policy: those take precedence.
+func (d *CompositeDelegate) HandleContext(ctx context.Context, action string, fromState string, toState string, args []interface{}) error {
+	var errs []error
+
+	for i, delegate := range d.delegates {
+		err := handleContext(ctx, delegate, action, fromState, toState, args)
+		if err == nil {
+			continue
+		}
+
+		if errors.Is(err, StopPropagation) {
+			if len(errs) > 0 {
+				break
+			}
+
 			// Error must be returned so that embedded composite delegates pass up the signal
 			return err
 		}
+
+		switch d.policy {
+		case FailFast:
+			return &ChildDelegateError{i, delegate, err}
+
+		case Aggregate:
+			errs = append(errs, &ChildDelegateError{i, delegate, err})
+		}
+	}
+
+	if len(errs) > 0 {
+		return &CompositeError{errs}
 	}
 
 	return nil
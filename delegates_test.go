@@ -1,11 +1,14 @@
 package fsm_test
 
 import (
+	"context"
+	"errors"
 	"testing"
 
 	"github.com/goph/fsm"
 	"github.com/goph/fsm/internal/mocks"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestActionMuxDelegate(t *testing.T) {
@@ -37,6 +40,17 @@ func TestActionMuxDelegate_NoAction(t *testing.T) {
 	delegate.AssertNotCalled(t, "Handle", "action", "fromState", "toState", []interface{}{"argument"})
 }
 
+func TestActionMuxDelegate_Actions(t *testing.T) {
+	delegates := map[string]fsm.Delegate{
+		"action2": new(mocks.Delegate),
+		"action1": new(mocks.Delegate),
+	}
+
+	amd := fsm.NewActionMuxDelegate(delegates)
+
+	assert.Equal(t, []string{"action1", "action2"}, amd.Actions())
+}
+
 func TestActionMuxDelegate_SetStateMachine(t *testing.T) {
 	delegate := new(mocks.Delegate)
 	smaDelegate := new(mocks.StateMachineAwareDelegate)
@@ -139,3 +153,191 @@ func TestCompositeDelegate_StopPropagation(t *testing.T) {
 	delegate1.AssertExpectations(t)
 	delegate2.AssertNotCalled(t, "Handle", "action", "fromState", "toState", []interface{}{"argument"})
 }
+
+func TestCompositeDelegate_BestEffort(t *testing.T) {
+	delegate1 := new(mocks.Delegate)
+	delegate1.On("Handle", "action", "fromState", "toState", []interface{}{"argument"}).Return(errors.New("error"))
+
+	delegate2 := new(mocks.Delegate)
+	delegate2.On("Handle", "action", "fromState", "toState", []interface{}{"argument"}).Return(nil)
+
+	cd := fsm.NewCompositeDelegate([]fsm.Delegate{delegate1, delegate2})
+
+	err := cd.Handle("action", "fromState", "toState", []interface{}{"argument"})
+
+	assert.NoError(t, err)
+
+	delegate1.AssertExpectations(t)
+	delegate2.AssertExpectations(t)
+}
+
+func TestCompositeDelegate_FailFast(t *testing.T) {
+	delegateErr := errors.New("error")
+
+	delegate1 := new(mocks.Delegate)
+	delegate1.On("Handle", "action", "fromState", "toState", []interface{}{"argument"}).Return(nil)
+
+	delegate2 := new(mocks.Delegate)
+	delegate2.On("Handle", "action", "fromState", "toState", []interface{}{"argument"}).Return(delegateErr)
+
+	delegate3 := new(mocks.Delegate)
+
+	cd := fsm.NewCompositeDelegateWithPolicy([]fsm.Delegate{delegate1, delegate2, delegate3}, fsm.FailFast)
+
+	err := cd.Handle("action", "fromState", "toState", []interface{}{"argument"})
+
+	var cerr *fsm.ChildDelegateError
+	require.True(t, errors.As(err, &cerr))
+
+	assert.Equal(t, 1, cerr.Index())
+	assert.Equal(t, delegate2, cerr.Delegate())
+	assert.True(t, errors.Is(err, delegateErr))
+	assert.EqualError(t, err, "delegate 1 (*mocks.Delegate): error")
+
+	delegate1.AssertExpectations(t)
+	delegate2.AssertExpectations(t)
+	delegate3.AssertNotCalled(t, "Handle", "action", "fromState", "toState", []interface{}{"argument"})
+}
+
+func TestCompositeDelegate_Aggregate(t *testing.T) {
+	delegateErr1 := errors.New("error 1")
+	delegateErr2 := errors.New("error 2")
+
+	delegate1 := new(mocks.Delegate)
+	delegate1.On("Handle", "action", "fromState", "toState", []interface{}{"argument"}).Return(delegateErr1)
+
+	delegate2 := new(mocks.Delegate)
+	delegate2.On("Handle", "action", "fromState", "toState", []interface{}{"argument"}).Return(nil)
+
+	delegate3 := new(mocks.Delegate)
+	delegate3.On("Handle", "action", "fromState", "toState", []interface{}{"argument"}).Return(delegateErr2)
+
+	cd := fsm.NewCompositeDelegateWithPolicy([]fsm.Delegate{delegate1, delegate2, delegate3}, fsm.Aggregate)
+
+	err := cd.Handle("action", "fromState", "toState", []interface{}{"argument"})
+
+	var cerr *fsm.CompositeError
+	require.True(t, errors.As(err, &cerr))
+	require.Len(t, cerr.Errors(), 2)
+
+	assert.True(t, errors.Is(err, delegateErr1))
+	assert.True(t, errors.Is(err, delegateErr2))
+	assert.EqualError(t, err, "2 delegates reported errors: delegate 0 (*mocks.Delegate): error 1; delegate 2 (*mocks.Delegate): error 2")
+
+	var childErr *fsm.ChildDelegateError
+	require.True(t, errors.As(cerr.Errors()[1], &childErr))
+	assert.Equal(t, 2, childErr.Index())
+
+	delegate1.AssertExpectations(t)
+	delegate2.AssertExpectations(t)
+	delegate3.AssertExpectations(t)
+}
+
+func TestCompositeDelegate_Aggregate_StopPropagation(t *testing.T) {
+	delegateErr := errors.New("error")
+
+	delegate1 := new(mocks.Delegate)
+	delegate1.On("Handle", "action", "fromState", "toState", []interface{}{"argument"}).Return(delegateErr)
+
+	delegate2 := new(mocks.Delegate)
+	delegate2.On("Handle", "action", "fromState", "toState", []interface{}{"argument"}).Return(fsm.StopPropagation)
+
+	delegate3 := new(mocks.Delegate)
+
+	cd := fsm.NewCompositeDelegateWithPolicy([]fsm.Delegate{delegate1, delegate2, delegate3}, fsm.Aggregate)
+
+	err := cd.Handle("action", "fromState", "toState", []interface{}{"argument"})
+
+	var cerr *fsm.CompositeError
+	require.True(t, errors.As(err, &cerr))
+	assert.True(t, errors.Is(err, delegateErr))
+
+	delegate3.AssertNotCalled(t, "Handle", "action", "fromState", "toState", []interface{}{"argument"})
+}
+
+func TestCompositeDelegate_DelegateError(t *testing.T) {
+	delegateErr := errors.New("error")
+
+	audit := new(mocks.Delegate)
+	audit.On("Handle", "action", "current_state", "next_state", []interface{}(nil)).Return(delegateErr)
+
+	cd := fsm.NewCompositeDelegateWithPolicy([]fsm.Delegate{audit}, fsm.Aggregate)
+
+	transitions := []fsm.Transition{
+		{
+			FromState: "current_state",
+			Event:     "event",
+			ToState:   "next_state",
+			Action:    "action",
+		},
+	}
+
+	sm := fsm.NewStateMachine(cd, transitions)
+
+	err := sm.Trigger("current_state", "event")
+
+	var derr *fsm.DelegateError
+	require.True(t, errors.As(err, &derr))
+
+	var childErr *fsm.ChildDelegateError
+	require.True(t, errors.As(err, &childErr))
+
+	assert.Equal(t, audit, childErr.Delegate())
+	assert.True(t, errors.Is(err, delegateErr))
+}
+
+func TestCompositeDelegate_UnknownPolicy(t *testing.T) {
+	assert.PanicsWithValue(t, `unknown error policy "failfast"`, func() {
+		fsm.NewCompositeDelegateWithPolicy(nil, "failfast")
+	})
+}
+
+func TestContextDelegateAdapter(t *testing.T) {
+	delegate := new(mocks.ContextDelegate)
+	delegate.On("HandleContext", context.Background(), "action", "fromState", "toState", []interface{}{"argument"}).Return(nil)
+
+	cda := fsm.NewContextDelegateAdapter(delegate)
+
+	cda.Handle("action", "fromState", "toState", []interface{}{"argument"})
+
+	delegate.AssertExpectations(t)
+}
+
+func TestActionMuxDelegate_HandleContext(t *testing.T) {
+	ctx := context.WithValue(context.Background(), contextKey("key"), "value")
+
+	delegate := new(mocks.ContextDelegate)
+	delegate.On("HandleContext", ctx, "action", "fromState", "toState", []interface{}{"argument"}).Return(nil)
+
+	delegates := map[string]fsm.Delegate{
+		"action": fsm.NewContextDelegateAdapter(delegate),
+	}
+
+	amd := fsm.NewActionMuxDelegate(delegates)
+
+	amd.HandleContext(ctx, "action", "fromState", "toState", []interface{}{"argument"})
+
+	delegate.AssertExpectations(t)
+}
+
+func TestCompositeDelegate_HandleContext(t *testing.T) {
+	ctx := context.WithValue(context.Background(), contextKey("key"), "value")
+
+	delegate1 := new(mocks.ContextDelegate)
+	delegate1.On("HandleContext", ctx, "action", "fromState", "toState", []interface{}{"argument"}).Return(nil)
+
+	delegate2 := new(mocks.Delegate)
+	delegate2.On("Handle", "action", "fromState", "toState", []interface{}{"argument"}).Return(nil)
+
+	delegates := []fsm.Delegate{
+		fsm.NewContextDelegateAdapter(delegate1),
+		delegate2,
+	}
+
+	cd := fsm.NewCompositeDelegate(delegates)
+
+	cd.HandleContext(ctx, "action", "fromState", "toState", []interface{}{"argument"})
+
+	delegate1.AssertExpectations(t)
+	delegate2.AssertExpectations(t)
+}
@@ -0,0 +1,275 @@
+// Package diagram renders state machine definitions as diagrams.
+package diagram
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/goph/fsm"
+)
+
+// Style describes how a state is drawn.
+//
+// Empty fields are not rendered.
+type Style struct {
+	// Color is the color of the outline.
+	Color string
+
+	// FillColor is the color of the background.
+	FillColor string
+
+	// Shape is the shape of the state (DOT only).
+	Shape string
+
+	// Bold draws a thicker outline.
+	Bold bool
+}
+
+// options holds the configuration of a diagram.
+type options struct {
+	initialStyle Style
+	finalStyle   Style
+	currentStyle Style
+
+	current []string
+}
+
+// Option configures a diagram.
+type Option func(o *options)
+
+// WithInitialStyle sets the style of the initial state.
+func WithInitialStyle(style Style) Option {
+	return func(o *options) {
+		o.initialStyle = style
+	}
+}
+
+// WithFinalStyle sets the style of final states.
+func WithFinalStyle(style Style) Option {
+	return func(o *options) {
+		o.finalStyle = style
+	}
+}
+
+// WithCurrentStyle sets the style of the highlighted current states.
+func WithCurrentStyle(style Style) Option {
+	return func(o *options) {
+		o.currentStyle = style
+	}
+}
+
+// WithCurrentStates highlights states as current states.
+func WithCurrentStates(states ...string) Option {
+	return func(o *options) {
+		o.current = append(o.current, states...)
+	}
+}
+
+// WithSubject highlights the current state of a subject.
+func WithSubject(subject fsm.Subject) Option {
+	return WithCurrentStates(subject.GetState())
+}
+
+// newOptions returns the default options overridden by the given ones.
+func newOptions(opts []Option) *options {
+	o := &options{
+		finalStyle: Style{
+			Shape: "doublecircle",
+		},
+		currentStyle: Style{
+			FillColor: "lightblue",
+			Bold:      true,
+		},
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// style returns the style of a state by merging the applicable styles.
+func (o *options) style(def fsm.Definition, state string) Style {
+	var style Style
+
+	if state == def.InitialState {
+		style = merge(style, o.initialStyle)
+	}
+
+	if def.State(state).Final {
+		style = merge(style, o.finalStyle)
+	}
+
+	for _, current := range o.current {
+		if current == state {
+			style = merge(style, o.currentStyle)
+		}
+	}
+
+	return style
+}
+
+// merge overrides the fields of a style with the non-empty fields of an other one.
+func merge(style Style, other Style) Style {
+	if other.Color != "" {
+		style.Color = other.Color
+	}
+
+	if other.FillColor != "" {
+		style.FillColor = other.FillColor
+	}
+
+	if other.Shape != "" {
+		style.Shape = other.Shape
+	}
+
+	style.Bold = style.Bold || other.Bold
+
+	return style
+}
+
+// label returns the edge label of a transition: "event [guard] / action, action".
+func label(t fsm.Transition) string {
+	parts := []string{t.Event}
+
+	if t.Guard != "" {
+		parts = append(parts, "["+t.Guard+"]")
+	}
+
+	var actions []string
+
+	for _, action := range append([]string{t.Action}, t.Actions...) {
+		if action != "" {
+			actions = append(actions, action)
+		}
+	}
+
+	if len(actions) > 0 {
+		parts = append(parts, "/", strings.Join(actions, ", "))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// children returns the states of a definition grouped by their parent ("" being the top level).
+//
+// History pseudo-states are not included.
+func children(def fsm.Definition) map[string][]string {
+	children := make(map[string][]string)
+
+	for _, state := range def.StateNames() {
+		s := def.State(state)
+		if s.History != "" {
+			continue
+		}
+
+		children[s.Parent] = append(children[s.Parent], state)
+	}
+
+	return children
+}
+
+// histories returns the history pseudo-states of a definition grouped by their parent.
+func histories(def fsm.Definition) map[string][]string {
+	histories := make(map[string][]string)
+
+	for _, state := range def.States {
+		if state.History != "" {
+			histories[state.Parent] = append(histories[state.Parent], state.Name)
+		}
+	}
+
+	return histories
+}
+
+// historyLabel returns the conventional label of a history pseudo-state.
+func historyLabel(history fsm.HistoryType) string {
+	if history == fsm.DeepHistory {
+		return "H*"
+	}
+
+	return "H"
+}
+
+// leaf returns the leaf state representing a (composite) state in diagrams which can only connect leaf states.
+func leaf(def fsm.Definition, children map[string][]string, state string) string {
+	for i := 0; i < len(def.States) && len(children[state]) > 0; i++ {
+		if initial := def.State(state).Initial; initial != "" {
+			state = initial
+		} else {
+			state = children[state][0]
+		}
+	}
+
+	return state
+}
+
+// expand returns the transitions of a definition with a single source state each.
+//
+// Transitions with multiple source states (and wildcard transitions) are rendered as an edge from each source state.
+// Transitions which can never be triggered in a source state (because others take precedence) are not rendered.
+func expand(def fsm.Definition) []fsm.Transition {
+	var transitions []fsm.Transition
+
+	for i, t := range def.Transitions {
+		for _, source := range def.Sources(t) {
+			if def.Preempted(i, source) {
+				continue
+			}
+
+			t := t
+			t.FromState = source
+			t.FromStates = nil
+			t.ExceptStates = nil
+
+			transitions = append(transitions, t)
+		}
+	}
+
+	return transitions
+}
+
+// transitionsByContainer groups transitions by the composite state containing them ("" being the top level).
+func transitionsByContainer(def fsm.Definition) map[string][]fsm.Transition {
+	transitions := make(map[string][]fsm.Transition)
+
+	for _, t := range expand(def) {
+		c := def.Domain(t.FromState, t.ToState)
+		transitions[c] = append(transitions[c], t)
+	}
+
+	return transitions
+}
+
+// notes returns the lines of the note of a transition derived from its metadata in key order.
+func notes(t fsm.Transition) []string {
+	keys := make([]string, 0, len(t.Metadata))
+	for key := range t.Metadata {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	lines := make([]string, len(keys))
+	for i, key := range keys {
+		lines[i] = fmt.Sprintf("%s: %s", key, t.Metadata[key])
+	}
+
+	return lines
+}
+
+// identifier returns an identifier which only contains letters, digits and underscores.
+func identifier(state string) string {
+	return strings.Map(
+		func(r rune) rune {
+			if r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+				return r
+			}
+
+			return '_'
+		},
+		state,
+	)
+}
@@ -0,0 +1,117 @@
+package diagram_test
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/goph/fsm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+// assertGolden compares the output with a golden file in testdata.
+func assertGolden(t *testing.T, name string, actual []byte) {
+	t.Helper()
+
+	path := filepath.Join("testdata", name)
+
+	if *update {
+		require.NoError(t, ioutil.WriteFile(path, actual, 0644))
+	}
+
+	expected, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, string(expected), string(actual))
+}
+
+type subject struct {
+	state string
+}
+
+func (s *subject) GetState() string {
+	return s.state
+}
+
+func turnstile() fsm.Definition {
+	sm := fsm.NewStateMachine(
+		nil,
+		[]fsm.Transition{
+			{FromState: "locked", Event: "insert_coin", ToState: "unlocked", Action: "unlock", Guard: "valid_coin"},
+			{FromState: "locked", Event: "push", ToState: "locked"},
+			{FromState: "unlocked", Event: "insert_coin", ToState: "unlocked"},
+			{FromState: "unlocked", Event: "push", ToState: "locked", Action: "lock"},
+			{FromState: "unlocked", Event: "break", ToState: "broken", Metadata: map[string]string{"owner": "maintenance", "sla": "4h"}},
+			{FromState: fsm.AnyState, ExceptStates: []string{"broken"}, Event: "power_off", ToState: "broken"},
+		},
+		fsm.WithInitialState("locked"),
+		fsm.WithStates([]fsm.State{{Name: "broken", Final: true}}),
+	)
+
+	return sm.Definition()
+}
+
+func order() fsm.Definition {
+	sm := fsm.NewStateMachine(
+		nil,
+		[]fsm.Transition{
+			{FromState: "new", Event: "pay", ToState: "in_fulfilment", Action: "charge", Actions: []string{"send_receipt"}},
+			{FromState: "picking", Event: "pick", ToState: "packing"},
+			{FromState: "packing", Event: "pack", ToState: "shipped"},
+			{FromState: "in_fulfilment", Event: "cancel", ToState: "cancelled", Action: "refund"},
+		},
+		fsm.WithInitialState("new"),
+		fsm.WithStates([]fsm.State{
+			{Name: "in_fulfilment", Initial: "picking"},
+			{Name: "picking", Parent: "in_fulfilment"},
+			{Name: "packing", Parent: "in_fulfilment"},
+			{Name: "shipped", Final: true},
+			{Name: "cancelled", Final: true},
+		}),
+	)
+
+	return sm.Definition()
+}
+
+func fulfilment() fsm.Definition {
+	sm := fsm.NewStateMachine(
+		nil,
+		[]fsm.Transition{
+			{FromState: "new", Event: "accept", ToState: "processing"},
+			{FromState: "awaiting_payment", Event: "pay", ToState: "paid"},
+			{FromState: "awaiting_shipment", Event: "ship", ToState: "shipped"},
+			{FromState: "processing", Event: "hold", ToState: "on_hold"},
+			{FromState: "on_hold", Event: "resume", ToState: "processing_history"},
+		},
+		fsm.WithInitialState("new"),
+		fsm.WithStates([]fsm.State{
+			{Name: "processing", Parallel: true},
+			{Name: "payment", Parent: "processing", Initial: "awaiting_payment"},
+			{Name: "awaiting_payment", Parent: "payment"},
+			{Name: "paid", Parent: "payment", Final: true},
+			{Name: "shipping", Parent: "processing", Initial: "awaiting_shipment"},
+			{Name: "awaiting_shipment", Parent: "shipping"},
+			{Name: "shipped", Parent: "shipping", Final: true},
+			{Name: "processing_history", Parent: "processing", History: fsm.DeepHistory},
+			{Name: "on_hold"},
+		}),
+	)
+
+	return sm.Definition()
+}
+
+// render renders a definition with a writer.
+func render(t *testing.T, write func(buf *bytes.Buffer) error) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	require.NoError(t, write(&buf))
+
+	return buf.Bytes()
+}
@@ -0,0 +1,142 @@
+package diagram
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/goph/fsm"
+)
+
+// WriteDOT renders a state machine definition in Graphviz DOT format.
+//
+// Edges are labelled as "event [guard] / action".
+// Composite states are rendered as clusters.
+func WriteDOT(w io.Writer, def fsm.Definition, opts ...Option) error {
+	o := newOptions(opts)
+	children := children(def)
+	histories := histories(def)
+
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintln(bw, "digraph fsm {")
+	fmt.Fprintln(bw, "\tcompound=true;")
+	fmt.Fprintln(bw, "\trankdir=LR;")
+	fmt.Fprintln(bw, "\tnode [shape=box, style=rounded];")
+
+	if def.InitialState != "" {
+		fmt.Fprintln(bw)
+		fmt.Fprintln(bw, "\t\"__initial\" [shape=point];")
+		fmt.Fprintf(bw, "\t\"__initial\" -> %s%s;\n", dotID(leaf(def, children, def.InitialState)), dotCluster(children, def.InitialState, "lhead"))
+	}
+
+	fmt.Fprintln(bw)
+	writeDOTStates(bw, def, o, children, histories, "", 1)
+
+	transitions := expand(def)
+
+	if len(transitions) > 0 {
+		fmt.Fprintln(bw)
+	}
+
+	for _, t := range transitions {
+		attributes := []string{"label=" + dotID(label(t))}
+
+		if cluster := dotCluster(children, t.FromState, "ltail"); cluster != "" {
+			attributes = append(attributes, strings.TrimPrefix(cluster, ", "))
+		}
+
+		if cluster := dotCluster(children, t.ToState, "lhead"); cluster != "" {
+			attributes = append(attributes, strings.TrimPrefix(cluster, ", "))
+		}
+
+		fmt.Fprintf(
+			bw,
+			"\t%s -> %s [%s];\n",
+			dotID(leaf(def, children, t.FromState)),
+			dotID(leaf(def, children, t.ToState)),
+			strings.Join(attributes, ", "),
+		)
+	}
+
+	fmt.Fprintln(bw, "}")
+
+	return bw.Flush()
+}
+
+// writeDOTStates renders the children of a state recursively.
+func writeDOTStates(w io.Writer, def fsm.Definition, o *options, children map[string][]string, histories map[string][]string, parent string, depth int) {
+	indent := strings.Repeat("\t", depth)
+
+	for _, state := range children[parent] {
+		if len(children[state]) > 0 {
+			fmt.Fprintf(w, "%ssubgraph %s {\n", indent, dotID("cluster_"+state))
+			fmt.Fprintf(w, "%s\tlabel=%s;\n", indent, dotID(state))
+
+			if s := def.State(state); s.Parallel {
+				fmt.Fprintf(w, "%s\tstyle=dashed;\n", indent)
+			}
+
+			writeDOTStates(w, def, o, children, histories, state, depth+1)
+
+			for _, history := range histories[state] {
+				fmt.Fprintf(
+					w,
+					"%s\t%s [shape=circle, label=%s];\n",
+					indent,
+					dotID(history),
+					dotID(historyLabel(def.State(history).History)),
+				)
+			}
+
+			fmt.Fprintf(w, "%s}\n", indent)
+
+			continue
+		}
+
+		attributes := dotAttributes(o.style(def, state))
+		if attributes == "" {
+			fmt.Fprintf(w, "%s%s;\n", indent, dotID(state))
+		} else {
+			fmt.Fprintf(w, "%s%s [%s];\n", indent, dotID(state), attributes)
+		}
+	}
+}
+
+// dotAttributes returns the DOT attributes of a style.
+func dotAttributes(style Style) string {
+	var attributes []string
+
+	if style.Shape != "" {
+		attributes = append(attributes, "shape="+dotID(style.Shape))
+	}
+
+	if style.Color != "" {
+		attributes = append(attributes, "color="+dotID(style.Color))
+	}
+
+	if style.FillColor != "" {
+		attributes = append(attributes, "style=\"rounded,filled\"", "fillcolor="+dotID(style.FillColor))
+	}
+
+	if style.Bold {
+		attributes = append(attributes, "penwidth=2")
+	}
+
+	return strings.Join(attributes, ", ")
+}
+
+// dotCluster returns the attribute connecting an edge to a cluster if the state is composite.
+func dotCluster(children map[string][]string, state string, attribute string) string {
+	if len(children[state]) == 0 {
+		return ""
+	}
+
+	return ", " + attribute + "=" + dotID("cluster_"+state)
+}
+
+// dotID returns a quoted DOT identifier.
+func dotID(id string) string {
+	return "\"" + strings.NewReplacer("\\", "\\\\", "\"", "\\\"").Replace(id) + "\""
+}
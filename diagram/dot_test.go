@@ -0,0 +1,47 @@
+package diagram_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/goph/fsm/diagram"
+)
+
+func TestWriteDOT(t *testing.T) {
+	out := render(t, func(buf *bytes.Buffer) error {
+		return diagram.WriteDOT(buf, turnstile())
+	})
+
+	assertGolden(t, "turnstile.dot.golden", out)
+}
+
+func TestWriteDOT_Styles(t *testing.T) {
+	out := render(t, func(buf *bytes.Buffer) error {
+		return diagram.WriteDOT(
+			buf,
+			turnstile(),
+			diagram.WithInitialStyle(diagram.Style{Color: "green"}),
+			diagram.WithFinalStyle(diagram.Style{Shape: "octagon", FillColor: "grey"}),
+			diagram.WithCurrentStyle(diagram.Style{Color: "red", Bold: true}),
+			diagram.WithSubject(&subject{"unlocked"}),
+		)
+	})
+
+	assertGolden(t, "turnstile_styles.dot.golden", out)
+}
+
+func TestWriteDOT_NestedStates(t *testing.T) {
+	out := render(t, func(buf *bytes.Buffer) error {
+		return diagram.WriteDOT(buf, order(), diagram.WithCurrentStates("packing"))
+	})
+
+	assertGolden(t, "order.dot.golden", out)
+}
+
+func TestWriteDOT_ParallelStates(t *testing.T) {
+	out := render(t, func(buf *bytes.Buffer) error {
+		return diagram.WriteDOT(buf, fulfilment())
+	})
+
+	assertGolden(t, "fulfilment.dot.golden", out)
+}
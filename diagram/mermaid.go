@@ -0,0 +1,176 @@
+package diagram
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/goph/fsm"
+)
+
+// WriteMermaid renders a state machine definition as a Mermaid state diagram (stateDiagram-v2).
+//
+// Edges are labelled as "event [guard] / action" and transition metadata is rendered as notes.
+// Composite states are rendered as nested states.
+func WriteMermaid(w io.Writer, def fsm.Definition, opts ...Option) error {
+	d := &mermaidDiagram{
+		def:         def,
+		options:     newOptions(opts),
+		children:    children(def),
+		histories:   histories(def),
+		transitions: transitionsByContainer(def),
+	}
+
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintln(bw, "stateDiagram-v2")
+
+	d.writeStates(bw, "", 1)
+	d.writeClasses(bw)
+
+	return bw.Flush()
+}
+
+type mermaidDiagram struct {
+	def         fsm.Definition
+	options     *options
+	children    map[string][]string
+	histories   map[string][]string
+	transitions map[string][]fsm.Transition
+}
+
+// writeStates renders the children of a state and the transitions between them recursively.
+func (d *mermaidDiagram) writeStates(w io.Writer, parent string, depth int) {
+	indent := strings.Repeat("\t", depth)
+
+	initial := d.def.InitialState
+	if parent != "" {
+		initial = d.def.State(parent).Initial
+	}
+
+	if initial != "" {
+		fmt.Fprintf(w, "%s[*] --> %s\n", indent, identifier(initial))
+	}
+
+	for i, state := range d.children[parent] {
+		// Regions of parallel states are separated
+		if i > 0 && d.def.State(parent).Parallel {
+			fmt.Fprintf(w, "%s--\n", indent)
+		}
+
+		id := identifier(state)
+		if id != state {
+			fmt.Fprintf(w, "%sstate %q as %s\n", indent, state, id)
+		}
+
+		if len(d.children[state]) > 0 {
+			fmt.Fprintf(w, "%sstate %s {\n", indent, id)
+
+			d.writeStates(w, state, depth+1)
+
+			parallel := d.def.State(state).Parallel
+			if !parallel {
+				d.writeHistories(w, state, indent+"\t")
+			}
+
+			fmt.Fprintf(w, "%s}\n", indent)
+
+			// Everything inside a parallel state belongs to one of its regions,
+			// so history states are rendered right after the concurrency block
+			if parallel {
+				d.writeHistories(w, state, indent)
+			}
+		} else if id == state {
+			fmt.Fprintf(w, "%s%s\n", indent, id)
+		}
+
+		if d.def.State(state).Final {
+			fmt.Fprintf(w, "%s%s --> [*]\n", indent, id)
+		}
+	}
+
+	for _, t := range d.transitions[parent] {
+		fmt.Fprintf(w, "%s%s --> %s : %s\n", indent, identifier(t.FromState), identifier(t.ToState), label(t))
+
+		if lines := notes(t); len(lines) > 0 {
+			fmt.Fprintf(w, "%snote right of %s\n", indent, identifier(t.FromState))
+			fmt.Fprintf(w, "%s\t%s\n", indent, t.Event)
+
+			for _, line := range lines {
+				fmt.Fprintf(w, "%s\t%s\n", indent, line)
+			}
+
+			fmt.Fprintf(w, "%send note\n", indent)
+		}
+	}
+}
+
+// writeHistories renders the history pseudo-states of a state.
+//
+// Mermaid doesn't support history states, so they are rendered as labelled states.
+func (d *mermaidDiagram) writeHistories(w io.Writer, state string, indent string) {
+	for _, history := range d.histories[state] {
+		fmt.Fprintf(w, "%sstate %q as %s\n", indent, historyLabel(d.def.State(history).History), identifier(history))
+	}
+}
+
+// writeClasses renders the styles of the initial, final and current states.
+func (d *mermaidDiagram) writeClasses(w io.Writer) {
+	classes := []struct {
+		name   string
+		style  Style
+		states []string
+	}{
+		{"initial", d.options.initialStyle, nil},
+		{"final", d.options.finalStyle, nil},
+		{"current", d.options.currentStyle, d.options.current},
+	}
+
+	if d.def.InitialState != "" {
+		classes[0].states = []string{d.def.InitialState}
+	}
+
+	for _, state := range d.def.StateNames() {
+		if d.def.State(state).Final {
+			classes[1].states = append(classes[1].states, state)
+		}
+	}
+
+	for _, class := range classes {
+		css := mermaidCSS(class.style)
+		if css == "" || len(class.states) == 0 {
+			continue
+		}
+
+		ids := make([]string, len(class.states))
+		for i, state := range class.states {
+			ids[i] = identifier(state)
+		}
+
+		fmt.Fprintln(w)
+		fmt.Fprintf(w, "\tclassDef %s %s\n", class.name, css)
+		fmt.Fprintf(w, "\tclass %s %s\n", strings.Join(ids, ","), class.name)
+	}
+}
+
+// mermaidCSS returns the CSS properties of a style.
+//
+// Shapes are not supported by Mermaid.
+func mermaidCSS(style Style) string {
+	var properties []string
+
+	if style.FillColor != "" {
+		properties = append(properties, "fill:"+style.FillColor)
+	}
+
+	if style.Color != "" {
+		properties = append(properties, "stroke:"+style.Color)
+	}
+
+	if style.Bold {
+		properties = append(properties, "stroke-width:2px")
+	}
+
+	return strings.Join(properties, ",")
+}
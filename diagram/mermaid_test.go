@@ -0,0 +1,46 @@
+package diagram_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/goph/fsm/diagram"
+)
+
+func TestWriteMermaid(t *testing.T) {
+	out := render(t, func(buf *bytes.Buffer) error {
+		return diagram.WriteMermaid(buf, turnstile())
+	})
+
+	assertGolden(t, "turnstile.mermaid.golden", out)
+}
+
+func TestWriteMermaid_Styles(t *testing.T) {
+	out := render(t, func(buf *bytes.Buffer) error {
+		return diagram.WriteMermaid(
+			buf,
+			turnstile(),
+			diagram.WithInitialStyle(diagram.Style{Color: "green"}),
+			diagram.WithFinalStyle(diagram.Style{FillColor: "grey"}),
+			diagram.WithSubject(&subject{"unlocked"}),
+		)
+	})
+
+	assertGolden(t, "turnstile_styles.mermaid.golden", out)
+}
+
+func TestWriteMermaid_NestedStates(t *testing.T) {
+	out := render(t, func(buf *bytes.Buffer) error {
+		return diagram.WriteMermaid(buf, order())
+	})
+
+	assertGolden(t, "order.mermaid.golden", out)
+}
+
+func TestWriteMermaid_ParallelStates(t *testing.T) {
+	out := render(t, func(buf *bytes.Buffer) error {
+		return diagram.WriteMermaid(buf, fulfilment())
+	})
+
+	assertGolden(t, "fulfilment.mermaid.golden", out)
+}
@@ -0,0 +1,139 @@
+package diagram
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/goph/fsm"
+)
+
+// WritePlantUML renders a state machine definition as a PlantUML state diagram.
+//
+// Edges are labelled as "event [guard] / action" and transition metadata is rendered as notes.
+// Composite states are rendered as nested states.
+func WritePlantUML(w io.Writer, def fsm.Definition, opts ...Option) error {
+	d := &plantUMLDiagram{
+		def:         def,
+		options:     newOptions(opts),
+		children:    children(def),
+		transitions: transitionsByContainer(def),
+	}
+
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintln(bw, "@startuml")
+	fmt.Fprintln(bw, "hide empty description")
+
+	d.writeStates(bw, "", 0)
+
+	fmt.Fprintln(bw, "@enduml")
+
+	return bw.Flush()
+}
+
+type plantUMLDiagram struct {
+	def         fsm.Definition
+	options     *options
+	children    map[string][]string
+	transitions map[string][]fsm.Transition
+}
+
+// writeStates renders the children of a state and the transitions between them recursively.
+func (d *plantUMLDiagram) writeStates(w io.Writer, parent string, depth int) {
+	indent := strings.Repeat("\t", depth)
+
+	initial := d.def.InitialState
+	if parent != "" {
+		initial = d.def.State(parent).Initial
+	}
+
+	if initial != "" {
+		fmt.Fprintf(w, "%s[*] --> %s\n", indent, identifier(initial))
+	}
+
+	for i, state := range d.children[parent] {
+		// Regions of parallel states are separated
+		if i > 0 && d.def.State(parent).Parallel {
+			fmt.Fprintf(w, "%s--\n", indent)
+		}
+
+		declaration := "state " + identifier(state)
+		if identifier(state) != state {
+			declaration = fmt.Sprintf("state %q as %s", state, identifier(state))
+		}
+
+		if color := plantUMLColor(d.options.style(d.def, state)); color != "" {
+			declaration += " " + color
+		}
+
+		if len(d.children[state]) > 0 {
+			fmt.Fprintf(w, "%s%s {\n", indent, declaration)
+
+			d.writeStates(w, state, depth+1)
+
+			fmt.Fprintf(w, "%s}\n", indent)
+		} else {
+			fmt.Fprintf(w, "%s%s\n", indent, declaration)
+		}
+
+		if d.def.State(state).Final {
+			fmt.Fprintf(w, "%s%s --> [*]\n", indent, identifier(state))
+		}
+	}
+
+	for _, t := range d.transitions[parent] {
+		fmt.Fprintf(w, "%s%s --> %s : %s\n", indent, identifier(t.FromState), d.target(t.ToState), label(t))
+
+		if lines := notes(t); len(lines) > 0 {
+			fmt.Fprintf(w, "%snote on link\n", indent)
+
+			for _, line := range lines {
+				fmt.Fprintf(w, "%s\t%s\n", indent, line)
+			}
+
+			fmt.Fprintf(w, "%send note\n", indent)
+		}
+	}
+}
+
+// target returns the target of a transition, rendering history pseudo-states as PlantUML history states.
+func (d *plantUMLDiagram) target(state string) string {
+	s := d.def.State(state)
+
+	switch s.History {
+	case fsm.ShallowHistory:
+		return identifier(s.Parent) + "[H]"
+
+	case fsm.DeepHistory:
+		return identifier(s.Parent) + "[H*]"
+	}
+
+	return identifier(state)
+}
+
+// plantUMLColor returns the color specification of a style.
+//
+// Shapes are not supported by PlantUML.
+func plantUMLColor(style Style) string {
+	var specs []string
+
+	if style.FillColor != "" {
+		specs = append(specs, style.FillColor)
+	}
+
+	if style.Color != "" {
+		specs = append(specs, "line:"+style.Color)
+	}
+
+	if style.Bold {
+		specs = append(specs, "line.bold")
+	}
+
+	if len(specs) == 0 {
+		return ""
+	}
+
+	return "#" + strings.Join(specs, ";")
+}
@@ -0,0 +1,46 @@
+package diagram_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/goph/fsm/diagram"
+)
+
+func TestWritePlantUML(t *testing.T) {
+	out := render(t, func(buf *bytes.Buffer) error {
+		return diagram.WritePlantUML(buf, turnstile())
+	})
+
+	assertGolden(t, "turnstile.plantuml.golden", out)
+}
+
+func TestWritePlantUML_Styles(t *testing.T) {
+	out := render(t, func(buf *bytes.Buffer) error {
+		return diagram.WritePlantUML(
+			buf,
+			turnstile(),
+			diagram.WithInitialStyle(diagram.Style{Color: "green"}),
+			diagram.WithFinalStyle(diagram.Style{FillColor: "grey"}),
+			diagram.WithSubject(&subject{"unlocked"}),
+		)
+	})
+
+	assertGolden(t, "turnstile_styles.plantuml.golden", out)
+}
+
+func TestWritePlantUML_NestedStates(t *testing.T) {
+	out := render(t, func(buf *bytes.Buffer) error {
+		return diagram.WritePlantUML(buf, order())
+	})
+
+	assertGolden(t, "order.plantuml.golden", out)
+}
+
+func TestWritePlantUML_ParallelStates(t *testing.T) {
+	out := render(t, func(buf *bytes.Buffer) error {
+		return diagram.WritePlantUML(buf, fulfilment())
+	})
+
+	assertGolden(t, "fulfilment.plantuml.golden", out)
+}
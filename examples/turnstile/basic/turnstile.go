@@ -15,8 +15,7 @@ func (t *Turnstile) GetState() string {
 
 // SetState sets the state of the Turnstile.
 //
-// Note: this is generally a huge mistake and leat, DO NOT DO IT.
-// This method is purely here for the sake of the examples.
+// It is called by the state machine to commit the new state.
 func (t *Turnstile) SetState(state string) {
 	t.state = state
 }
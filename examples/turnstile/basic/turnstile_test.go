@@ -9,8 +9,8 @@ func Example_insertACoinAndPass() {
 	t := basic.New()
 	stateMachine := turnstile.NewStateMachine()
 
-	stateMachine.Trigger(t.GetState(), "coin_inserted", t)
-	stateMachine.Trigger(t.GetState(), "pushed", t)
+	stateMachine.TriggerSubject(t, "coin_inserted")
+	stateMachine.TriggerSubject(t, "pushed")
 
 	// Output:
 	// Coin inserted, you shall pass
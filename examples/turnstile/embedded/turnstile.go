@@ -1,4 +1,7 @@
 // Package embedded embeds the state machine into the subject (turnstile) and exposes commands hiding the state machine.
+//
+// The states, events, the state machine constructor and the commands are generated from turnstile.yaml,
+// so the commands cannot get out of sync with the definition.
 package embedded
 
 import (
@@ -6,9 +9,11 @@ import (
 	"github.com/goph/fsm/examples/turnstile"
 )
 
+//go:generate go run ../../../cmd/fsm-gen -type Turnstile -constructor newStateMachine -method coin_inserted=InsertCoin -method pushed=Push turnstile.yaml
+
 // Turnstile is a a mechanical gate consisting of revolving horizontal arms fixed to a vertical post, allowing only one person at a time to pass through if the entry fee is paid.
 type Turnstile struct {
-	state string
+	state State
 
 	stateMachine *fsm.StateMachine
 }
@@ -16,31 +21,20 @@ type Turnstile struct {
 // New returns a new Turnstile.
 func New() *Turnstile {
 	return &Turnstile{
-		state: turnstile.Locked,
+		state: StateLocked,
 
-		stateMachine: turnstile.NewStateMachine(),
+		stateMachine: newStateMachine(turnstile.NewDelegate()),
 	}
 }
 
 // GetState returns the current state of the turnstile.
 func (t *Turnstile) GetState() string {
-	return t.state
+	return t.state.String()
 }
 
 // SetState sets the state of the Turnstile.
 //
-// Note: this is generally a huge mistake and leat, DO NOT DO IT.
-// This method is purely here for the sake of the examples.
+// It is called by the state machine to commit the new state.
 func (t *Turnstile) SetState(state string) {
-	t.state = state
-}
-
-// InsertCoin fires a coin_inserted event for the turnstile.
-func (t *Turnstile) InsertCoin() {
-	t.stateMachine.TriggerSubject(t, "coin_inserted")
-}
-
-// Push fires a pushed event for the turnstile.
-func (t *Turnstile) Push() {
-	t.stateMachine.TriggerSubject(t, "pushed")
+	t.state = State(state)
 }
@@ -3,13 +3,15 @@ Package turnstile provides an example for the state machine library.
 
 A turnstile is a a mechanical gate consisting of revolving horizontal arms fixed to a vertical post, allowing only one person at a time to pass through if the entry fee is paid.
 
-This example is separated into three parts:
+This example is separated into four parts:
 
 Basic: explains the basic usage of a state machine
 
-Embedded: embeds the state machine into the subject (turnstile) and exposes commands hiding the state machine
+Embedded: embeds the state machine into the subject (turnstile) and exposes commands hiding the state machine (generated from a definition file by fsm-gen)
 
 Pool: embeds a state machine pool into the subject. Although using a state machine should be safe for concurrent usage, delegates are out of control of this library and by using pools one can make sure that the state machine is in fact concurrent safe.
+
+Typed: uses the type-safe state machine API, so delegates don't need type assertions
 */
 package turnstile
 
@@ -27,23 +29,12 @@ const (
 	Unlocked = "unlocked"
 )
 
-// Turnstile is a a mechanical gate consisting of revolving horizontal arms fixed to a vertical post, allowing only one person at a time to pass through if the entry fee is paid.
-type Turnstile interface {
-	// SetState sets the state of the Turnstile.
-	//
-	// Note: this is generally a huge mistake and leat, DO NOT DO IT.
-	// This method is purely here for the sake of the examples.
-	SetState(state string)
-}
-
 // NewStateMachine returns a new StateMachine for a turnstile.
+//
+// Turnstiles are expected to be mutable subjects: the state machine commits their new state.
 func NewStateMachine() *fsm.StateMachine {
 	return fsm.NewStateMachine(
-		fsm.NewActionMuxDelegate(map[string]fsm.Delegate{
-			"coin":   &coinAction{},
-			"pass":   &passAction{},
-			"nopass": &noPassAction{},
-		}),
+		NewDelegate(),
 		[]fsm.Transition{
 			{
 				FromState: Locked,
@@ -73,16 +64,21 @@ func NewStateMachine() *fsm.StateMachine {
 	)
 }
 
+// NewDelegate returns the delegate handling the actions of a turnstile.
+func NewDelegate() *fsm.ActionMuxDelegate {
+	return fsm.NewActionMuxDelegate(map[string]fsm.Delegate{
+		"coin":   &coinAction{},
+		"pass":   &passAction{},
+		"nopass": &noPassAction{},
+	})
+}
+
 // coinAction is the delegate called when a coin is placed in the machine.
 type coinAction struct{}
 
 // Handle unlocks the turnstile.
 func (d *coinAction) Handle(action string, fromState string, toState string, args []interface{}) error {
-	if turnstile, ok := args[0].(Turnstile); ok {
-		turnstile.SetState(toState)
-
-		fmt.Println("Coin inserted, you shall pass")
-	}
+	fmt.Println("Coin inserted, you shall pass")
 
 	return nil
 }
@@ -90,13 +86,9 @@ func (d *coinAction) Handle(action string, fromState string, toState string, arg
 // passAction is the delegate called when the turnstile is pushed.
 type passAction struct{}
 
-// Handle unlocks the turnstile.
+// Handle locks the turnstile.
 func (d *passAction) Handle(action string, fromState string, toState string, args []interface{}) error {
-	if turnstile, ok := args[0].(Turnstile); ok {
-		turnstile.SetState(toState)
-
-		fmt.Println("Passed the gate, coin please")
-	}
+	fmt.Println("Passed the gate, coin please")
 
 	return nil
 }
@@ -0,0 +1,118 @@
+// Package typed uses the type-safe state machine API, so delegates don't need type assertions.
+package typed
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/goph/fsm"
+)
+
+// State is the state of a turnstile.
+type State string
+
+// Event is an event fired for a turnstile.
+type Event string
+
+const (
+	// Locked represents the locked turnstile state.
+	Locked State = "locked"
+
+	// Unlocked represents the unlocked turnstile state.
+	Unlocked State = "unlocked"
+)
+
+const (
+	// CoinInserted is fired when a coin is inserted into the turnstile.
+	CoinInserted Event = "coin_inserted"
+
+	// Pushed is fired when the turnstile is pushed.
+	Pushed Event = "pushed"
+)
+
+// Turnstile is a a mechanical gate consisting of revolving horizontal arms fixed to a vertical post, allowing only one person at a time to pass through if the entry fee is paid.
+type Turnstile struct {
+	state State
+}
+
+// New returns a new Turnstile.
+func New() *Turnstile {
+	return &Turnstile{
+		state: Locked,
+	}
+}
+
+// GetState returns the current state of the turnstile.
+func (t *Turnstile) GetState() State {
+	return t.state
+}
+
+// SetState sets the state of the Turnstile.
+//
+// It is called by the state machine to commit the new state.
+func (t *Turnstile) SetState(state State) {
+	t.state = state
+}
+
+// StateMachine is a type-safe state machine for turnstiles.
+//
+// Events don't carry any payload.
+type StateMachine = fsm.Machine[State, Event, *Turnstile, struct{}]
+
+// NewStateMachine returns a new StateMachine for a turnstile.
+func NewStateMachine() *StateMachine {
+	return fsm.NewMachine[State, Event, *Turnstile, struct{}](
+		fsm.NewTypedActionMuxDelegate(map[string]fsm.TypedDelegate[State, *Turnstile, struct{}]{
+			"coin":   fsm.TypedDelegateFunc[State, *Turnstile, struct{}](coin),
+			"pass":   fsm.TypedDelegateFunc[State, *Turnstile, struct{}](pass),
+			"nopass": fsm.TypedDelegateFunc[State, *Turnstile, struct{}](noPass),
+		}),
+		[]fsm.TypedTransition[State, Event]{
+			{
+				FromState: Locked,
+				Event:     CoinInserted,
+				ToState:   Unlocked,
+				Action:    "coin",
+			},
+			{
+				FromState: Unlocked,
+				Event:     CoinInserted,
+				ToState:   Unlocked,
+				Action:    "coin",
+			},
+			{
+				FromState: Unlocked,
+				Event:     Pushed,
+				ToState:   Locked,
+				Action:    "pass",
+			},
+			{
+				FromState: Locked,
+				Event:     Pushed,
+				ToState:   Locked,
+				Action:    "nopass",
+			},
+		},
+	)
+}
+
+// coin is called when a coin is placed in the machine.
+func coin(ctx context.Context, action string, fromState State, toState State, turnstile *Turnstile, _ struct{}) error {
+	fmt.Println("Coin inserted, you shall pass")
+
+	return nil
+}
+
+// pass is called when the turnstile is pushed.
+func pass(ctx context.Context, action string, fromState State, toState State, turnstile *Turnstile, _ struct{}) error {
+	fmt.Println("Passed the gate, coin please")
+
+	return nil
+}
+
+// noPass is called when the turnstile is pushed.
+func noPass(ctx context.Context, action string, fromState State, toState State, turnstile *Turnstile, _ struct{}) error {
+	fmt.Println("You shall not pass")
+
+	return nil
+}
@@ -0,0 +1,29 @@
+package typed_test
+
+import (
+	"context"
+
+	"github.com/goph/fsm/examples/turnstile/typed"
+)
+
+func Example_insertACoinAndPass() {
+	t := typed.New()
+	stateMachine := typed.NewStateMachine()
+
+	stateMachine.Trigger(context.Background(), t, typed.CoinInserted, struct{}{})
+	stateMachine.Trigger(context.Background(), t, typed.Pushed, struct{}{})
+
+	// Output:
+	// Coin inserted, you shall pass
+	// Passed the gate, coin please
+}
+
+func Example_cannotPassWhenLocked() {
+	t := typed.New()
+	stateMachine := typed.NewStateMachine()
+
+	stateMachine.Trigger(context.Background(), t, typed.Pushed, struct{}{})
+
+	// Output:
+	// You shall not pass
+}
@@ -4,8 +4,10 @@
 package fsm
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"time"
 )
 
 // Delegate is responsible for handling actions whenever a transition has one.
@@ -25,6 +27,50 @@ type Delegate interface {
 	Handle(action string, fromState string, toState string, args []interface{}) error
 }
 
+// ContextDelegate is a Delegate which receives the context of the trigger.
+//
+// The state machine calls HandleContext instead of Handle when a delegate implements both.
+type ContextDelegate interface {
+	// HandleContext handles transition actions.
+	//
+	// See Delegate.Handle for details.
+	HandleContext(ctx context.Context, action string, fromState string, toState string, args []interface{}) error
+}
+
+// NewContextDelegateAdapter adapts a ContextDelegate to the Delegate interface.
+//
+// The adapter can be used wherever a Delegate is expected (eg. in an ActionMuxDelegate).
+// When called without a context, the delegate receives an empty context.
+func NewContextDelegateAdapter(delegate ContextDelegate) Delegate {
+	return &contextDelegateAdapter{delegate}
+}
+
+// contextDelegateAdapter adapts a ContextDelegate to the Delegate interface.
+type contextDelegateAdapter struct {
+	ContextDelegate
+}
+
+// Handle calls the underlying delegate with an empty context.
+func (d *contextDelegateAdapter) Handle(action string, fromState string, toState string, args []interface{}) error {
+	return d.HandleContext(context.Background(), action, fromState, toState, args)
+}
+
+// SetStateMachine sets the current state machine in the underlying delegate if it's aware of it.
+func (d *contextDelegateAdapter) SetStateMachine(sm *StateMachine) {
+	if smaDelegate, ok := d.ContextDelegate.(StateMachineAwareDelegate); ok {
+		smaDelegate.SetStateMachine(sm)
+	}
+}
+
+// handleContext calls a delegate with the context if it's context-aware.
+func handleContext(ctx context.Context, delegate Delegate, action string, fromState string, toState string, args []interface{}) error {
+	if cDelegate, ok := delegate.(ContextDelegate); ok {
+		return cDelegate.HandleContext(ctx, action, fromState, toState, args)
+	}
+
+	return delegate.Handle(action, fromState, toState, args)
+}
+
 // StateMachineAwareDelegate plays a role when a state transition itself requires another state transition to happen.
 //
 // For example: some kind of business validation fails and a workflow needs to be terminated immediately.
@@ -36,12 +82,99 @@ type StateMachineAwareDelegate interface {
 	SetStateMachine(sm *StateMachine)
 }
 
+// AnyState can be used as the source state of a transition which can be triggered in any state.
+//
+// Explicit transitions (declared on the current state or one of its ancestors) take precedence over wildcard ones.
+// Guards of wildcard transitions receive the current state as the from state.
+const AnyState = "*"
+
 // Transition represents a state transition.
 type Transition struct {
 	FromState string
 	Event     string
 	ToState   string
 	Action    string
+
+	// FromStates are additional source states the transition can be triggered in,
+	// as if the transition was declared for each of them.
+	FromStates []string
+
+	// ExceptStates are the states (and their descendants) a wildcard transition (from AnyState) cannot be triggered in.
+	ExceptStates []string
+
+	// Actions are executed in order after Action.
+	//
+	// Just like delegates of a CompositeDelegate, an action returning StopPropagation stops executing further actions
+	// (state hooks are still executed) and an action returning an error fails the transition
+	// (the failing action is reported by DelegateError.Action).
+	Actions []string
+
+	// Guard is the name of a guard which has to pass for the transition to be selected.
+	//
+	// An empty guard always passes.
+	Guard string
+
+	// Metadata holds arbitrary information about the transition (eg. for documentation).
+	//
+	// It is not used by the state machine, but diagrams render it as notes.
+	Metadata map[string]string
+}
+
+// ErrorCode is a stable, machine-readable identifier of an error (eg. for JSON API responses).
+type ErrorCode string
+
+const (
+	// CodeInvalidTransition identifies InvalidTransitionError.
+	CodeInvalidTransition ErrorCode = "invalid_transition"
+
+	// CodeGuardRejected identifies GuardRejectedError.
+	CodeGuardRejected ErrorCode = "guard_rejected"
+
+	// CodeDelegateFailed identifies DelegateError.
+	CodeDelegateFailed ErrorCode = "delegate_failed"
+
+	// CodeConflict identifies ConflictError.
+	CodeConflict ErrorCode = "conflict"
+
+	// CodeCompoundState identifies CompoundStateError.
+	CodeCompoundState ErrorCode = "compound_state"
+)
+
+// Sentinel errors matching the error types of the state machine using errors.Is.
+var (
+	// ErrInvalidTransition matches InvalidTransitionError.
+	ErrInvalidTransition = errors.New("invalid transition")
+
+	// ErrGuardRejected matches GuardRejectedError.
+	ErrGuardRejected = errors.New("guard rejected transition")
+
+	// ErrDelegateFailed matches DelegateError.
+	ErrDelegateFailed = errors.New("delegate failed")
+
+	// ErrConflict matches ConflictError.
+	ErrConflict = errors.New("state changed concurrently")
+
+	// ErrCompoundState matches CompoundStateError.
+	ErrCompoundState = errors.New("transition enters multiple states")
+)
+
+// TransitionError is implemented by every error which occurs during a state transition.
+//
+// It can be extracted from wrapped errors using errors.As.
+type TransitionError interface {
+	error
+
+	// CurrentState returns the current state.
+	CurrentState() string
+
+	// Event returns the triggered event.
+	Event() string
+
+	// Arguments returns the arguments of the event.
+	Arguments() []interface{}
+
+	// Code returns the machine-readable code of the error.
+	Code() ErrorCode
 }
 
 // transitionError represents an error which occurs during a state transition, regardless whether the transitions was successful or not.
@@ -56,12 +189,12 @@ func (e *transitionError) CurrentState() string {
 	return e.currentState
 }
 
-// Event returns the current state.
+// Event returns the triggered event.
 func (e *transitionError) Event() string {
 	return e.event
 }
 
-// Arguments returns the current state.
+// Arguments returns the arguments of the event.
 func (e *transitionError) Arguments() []interface{} {
 	return e.args
 }
@@ -76,6 +209,37 @@ type InvalidTransitionError struct {
 	*transitionError
 }
 
+// Code returns CodeInvalidTransition.
+func (e *InvalidTransitionError) Code() ErrorCode {
+	return CodeInvalidTransition
+}
+
+// Is matches ErrInvalidTransition.
+func (e *InvalidTransitionError) Is(target error) bool {
+	return target == ErrInvalidTransition
+}
+
+// GuardRejectedError is returned when there are transitions for a state-event pair,
+// but all of them are rejected by their guards.
+type GuardRejectedError struct {
+	*transitionError
+}
+
+// Code returns CodeGuardRejected.
+func (e *GuardRejectedError) Code() ErrorCode {
+	return CodeGuardRejected
+}
+
+// Is matches ErrGuardRejected.
+func (e *GuardRejectedError) Is(target error) bool {
+	return target == ErrGuardRejected
+}
+
+// Error returns the formatted error message.
+func (e *GuardRejectedError) Error() string {
+	return fmt.Sprintf("guards rejected every transition from %q state triggered by %q event", e.currentState, e.event)
+}
+
 // DelegateError wraps an error returned by a delegate.
 type DelegateError struct {
 	*transitionError
@@ -90,6 +254,23 @@ func (e *DelegateError) Cause() error {
 	return e.err
 }
 
+// Unwrap returns the error returned by the delegate.
+//
+// It allows errors.Is and errors.As to inspect the error (eg. a CompositeError).
+func (e *DelegateError) Unwrap() error {
+	return e.err
+}
+
+// Code returns CodeDelegateFailed.
+func (e *DelegateError) Code() ErrorCode {
+	return CodeDelegateFailed
+}
+
+// Is matches ErrDelegateFailed.
+func (e *DelegateError) Is(target error) bool {
+	return target == ErrDelegateFailed
+}
+
 // Error returns the formatted error message.
 func (e *DelegateError) Error() string {
 	return fmt.Sprintf(
@@ -111,20 +292,56 @@ func (e *DelegateError) Action() string {
 }
 
 // StopPropagation can be returned by delegates to indicate that any further delegates should not be executed.
+//
+// During a transition it skips the remaining transition actions,
+// but the exit and enter hooks of the states are executed regardless and the transition succeeds.
+// It is detected using errors.Is, so it can be wrapped.
 var StopPropagation = errors.New("stop propagation")
 
 // StateMachine handles state transitions when an event is fired and calls the underlying delegate.
 type StateMachine struct {
 	delegate    Delegate
+	guard       Guard
 	transitions []Transition
+	index       map[transitionKey][]*compiledTransition
+	wildcards   map[string][]*compiledTransition
+	stateNames  []string
+	hasHistory  map[string]bool
+
+	hierarchy
+
+	initialState string
+
+	listeners []Listener
+
+	recoverPanics bool
+}
+
+// Option configures a StateMachine.
+type Option func(sm *StateMachine)
+
+// WithGuard sets the guard used to evaluate transition guards.
+func WithGuard(guard Guard) Option {
+	return func(sm *StateMachine) {
+		sm.guard = guard
+	}
 }
 
 // NewStateMachine returns a new StateMachine.
-func NewStateMachine(delegate Delegate, transitions []Transition) *StateMachine {
+func NewStateMachine(delegate Delegate, transitions []Transition, opts ...Option) *StateMachine {
 	stateMachine := &StateMachine{
-		transitions: transitions,
+		// Transitions are copied, so that later modifications don't corrupt the index
+		transitions: append([]Transition(nil), transitions...),
+		hasHistory:  make(map[string]bool),
+		hierarchy:   newHierarchy(nil),
+	}
+
+	for _, opt := range opts {
+		opt(stateMachine)
 	}
 
+	stateMachine.compile()
+
 	if smaDelegate, ok := delegate.(StateMachineAwareDelegate); ok {
 		smaDelegate.SetStateMachine(stateMachine)
 	}
@@ -135,63 +352,383 @@ func NewStateMachine(delegate Delegate, transitions []Transition) *StateMachine
 }
 
 // Trigger fires an event and calls the underlying delegate.
+//
+// When the current state is nested into other states,
+// the innermost state declaring a matching transition wins.
 func (sm *StateMachine) Trigger(currentState string, event string, args ...interface{}) error {
-	t := sm.findTransition(currentState, event)
-	if t == nil {
-		return &InvalidTransitionError{
-			&transitionError{
-				currentState: currentState,
-				event:        event,
-				args:         args,
-			},
-		}
+	return sm.TriggerContext(context.Background(), currentState, event, args...)
+}
+
+// TriggerContext fires an event and calls the underlying delegate with a context.
+//
+// The context is checked before the transition happens:
+// once the transition started, cancellation is up to the delegates.
+func (sm *StateMachine) TriggerContext(ctx context.Context, currentState string, event string, args ...interface{}) error {
+	_, err := sm.trigger(ctx, nil, []string{currentState}, event, args, nil, false)
+
+	return err
+}
+
+// trigger fires an event in every region of the active states and returns the states active after the transitions.
+//
+// History of the left composite states is recorded unless history is nil.
+// When single is true, transitions entering multiple states (eg. the regions of a parallel state)
+// are rejected before executing any actions.
+// The subject (if any) is only used to notify listeners.
+// The returned states might be shared with the state machine, so they must not be modified.
+func (sm *StateMachine) trigger(
+	ctx context.Context,
+	subject interface{},
+	currentStates []string,
+	event string,
+	args []interface{},
+	history History,
+	single bool,
+) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return currentStates, err
 	}
 
-	if t.Action != "" {
-		err := sm.delegate.Handle(t.Action, t.FromState, t.ToState, args)
+	type step struct {
+		state      string
+		transition *compiledTransition
+	}
+
+	// Most of the time there is a single region: avoid allocating in that case
+	var stepBuf [1]step
+	steps := stepBuf[:0]
+
+	var errs []error
+
+	for _, state := range currentStates {
+		t, err := sm.resolveTransition(state, event, args)
 		if err != nil {
-			if err == StopPropagation {
-				return nil
+			errs = append(errs, err)
+
+			continue
+		}
+
+		// Transitions declared on a common ancestor of multiple regions only fire once
+		var fired bool
+		for _, s := range steps {
+			if s.transition == t {
+				fired = true
+			}
+		}
+
+		if !fired {
+			steps = append(steps, step{state, t})
+		}
+	}
+
+	// Regions without a matching transition are only reported when none of the regions could handle the event
+	if len(steps) == 0 {
+		// Every region failed, so errors are in the order of the regions
+		if len(sm.listeners) > 0 {
+			for i, state := range currentStates {
+				attempt := newAttempt(subject, state, event, args, nil)
+				sm.notifyAfter(ctx, attempt, sm.notifyBefore(ctx, attempt), errs[i])
 			}
+		}
+
+		return currentStates, combineErrors(errs)
+	}
 
-			return &DelegateError{
+	// There is a single step for a single current state
+	if single {
+		if targets := sm.targets(steps[0].transition, history); len(targets) != 1 {
+			err := &CompoundStateError{
 				transitionError: &transitionError{
-					currentState: currentState,
+					currentState: currentStates[0],
 					event:        event,
 					args:         args,
 				},
 
-				err:       err,
-				nextState: t.ToState,
-				action:    t.Action,
+				nextStates: targets,
 			}
+
+			if len(sm.listeners) > 0 {
+				attempt := newAttempt(subject, steps[0].state, event, args, steps[0].transition)
+				sm.notifyAfter(ctx, attempt, sm.notifyBefore(ctx, attempt), err)
+			}
+
+			return currentStates, err
 		}
 	}
 
-	return nil
+	errs = nil
+	nextStates := currentStates
+
+	for _, s := range steps {
+		// The state might have been left by a transition of an other region
+		if !containsState(nextStates, s.state) {
+			continue
+		}
+
+		var attempt Attempt
+		var start time.Time
+
+		if len(sm.listeners) > 0 {
+			attempt = newAttempt(subject, s.state, event, args, s.transition)
+			start = sm.notifyBefore(ctx, attempt)
+		}
+
+		states, err := sm.transition(ctx, nextStates, s.state, s.transition, event, args, history)
+
+		if len(sm.listeners) > 0 {
+			sm.notifyAfter(ctx, attempt, start, err)
+		}
+
+		if err != nil {
+			errs = append(errs, err)
+
+			continue
+		}
+
+		nextStates = states
+	}
+
+	return nextStates, combineErrors(errs)
+}
+
+// transition executes a transition and returns the states active after it.
+func (sm *StateMachine) transition(
+	ctx context.Context,
+	activeStates []string,
+	currentState string,
+	ct *compiledTransition,
+	event string,
+	args []interface{},
+	history History,
+) ([]string, error) {
+	t := ct.transition
+	domain := ct.domain
+
+	if ct.wildcard {
+		domain = sm.transitionDomain(currentState, t.ToState)
+	}
+
+	targets := sm.targets(ct, history)
+
+	// The next state is ambiguous when the target has orthogonal regions
+	nextState := t.ToState
+	if len(targets) == 1 {
+		nextState = targets[0]
+	}
+
+	var exited, nextStates []string
+
+	// The only active state is always left
+	if len(activeStates) == 1 {
+		exited = activeStates
+		nextStates = targets
+	} else {
+		nextStates = make([]string, 0, len(activeStates)+len(targets))
+
+		for _, state := range activeStates {
+			if domain != "" && !sm.isAncestor(domain, state) {
+				nextStates = append(nextStates, state)
+
+				continue
+			}
+
+			// Target states take the place of the first exited state
+			if len(exited) == 0 {
+				nextStates = append(nextStates, targets...)
+			}
+
+			exited = append(exited, state)
+		}
+	}
+
+	var err error
+	var stopped bool
+
+	sm.eachAction(exited, t, targets, domain, func(action string, hook bool) bool {
+		// State hooks are executed even if a previous action stopped propagation
+		if stopped && !hook {
+			return true
+		}
+
+		err = sm.handle(ctx, action, currentState, nextState, args)
+		if err == nil {
+			return true
+		}
+
+		// Panics fail the transition, even if the delegate panicked with StopPropagation
+		if _, panicked := err.(*DelegatePanicError); !panicked && errors.Is(err, StopPropagation) {
+			err = nil
+			stopped = true
+
+			return true
+		}
+
+		err = &DelegateError{
+			transitionError: &transitionError{
+				currentState: currentState,
+				event:        event,
+				args:         args,
+			},
+
+			err:       err,
+			nextState: nextState,
+			action:    action,
+		}
+
+		return false
+	})
+
+	if err != nil {
+		return activeStates, err
+	}
+
+	if history != nil {
+		sm.recordHistory(history, exited, domain)
+	}
+
+	return nextStates, nil
 }
 
-// findTransition returns a transition if there is one for the state-event pair.
-func (sm *StateMachine) findTransition(fromState string, event string) *Transition {
-	for _, t := range sm.transitions {
-		if t.FromState == fromState && t.Event == event {
-			return &t
+// resolveTransition looks for a transition starting from the current state and walking up its ancestors.
+func (sm *StateMachine) resolveTransition(currentState string, event string, args []interface{}) (*compiledTransition, error) {
+	var rejected bool
+
+	state := currentState
+
+	for depth := 0; state != "" && depth <= len(sm.states); depth++ {
+		if transitions := sm.findTransitions(state, event); len(transitions) > 0 {
+			// Guards see the current state, just like delegates, even if the transition is declared on an ancestor
+			if t := sm.selectTransition(transitions, currentState, args); t != nil {
+				return t, nil
+			}
+
+			rejected = true
+		}
+
+		state = sm.states[state].Parent
+	}
+
+	// Wildcard transitions are only selected when there is no explicit one
+	for _, ct := range sm.wildcards[event] {
+		if sm.excludes(ct.transition, currentState) {
+			continue
+		}
+
+		if sm.checkGuard(ct.transition, currentState, args) {
+			return ct, nil
+		}
+
+		rejected = true
+	}
+
+	terr := &transitionError{
+		currentState: currentState,
+		event:        event,
+		args:         args,
+	}
+
+	if rejected {
+		return nil, &GuardRejectedError{terr}
+	}
+
+	return nil, &InvalidTransitionError{terr}
+}
+
+// selectTransition returns the first transition whose guard passes in a state.
+func (sm *StateMachine) selectTransition(transitions []*compiledTransition, state string, args []interface{}) *compiledTransition {
+	for _, ct := range transitions {
+		if sm.checkGuard(ct.transition, state, args) {
+			return ct
 		}
 	}
 
 	return nil
 }
 
+// checkGuard checks whether the guard of a transition (triggered in a state) passes.
+func (sm *StateMachine) checkGuard(t *Transition, state string, args []interface{}) bool {
+	if t.Guard == "" {
+		return true
+	}
+
+	// Guarded transitions can never pass without a guard to evaluate them
+	return sm.guard != nil && sm.guard.Check(t.Guard, state, t.ToState, args)
+}
+
 // Subject represents a stateful structure exposing it's current state.
 type Subject interface {
+	// GetState returns the innermost (leaf) state of the subject.
 	GetState() string
 }
 
+// MutableSubject is a Subject whose state is committed by the state machine.
+type MutableSubject interface {
+	Subject
+
+	// SetState sets the state of the subject.
+	SetState(state string)
+}
+
 // TriggerSubject triggers an event using the Subject's current state.
 //
 // It also passes the subject as the first argument.
+// When the subject is a MutableSubject, the new state is committed after the actions succeeded.
+// When the subject is a VersionedSubject, the new state is committed using compare-and-swap.
+// The state is left untouched when the transition fails.
+// Transitions entering multiple states (eg. the regions of a parallel state) fail with a CompoundStateError
+// without executing any actions: use TriggerCompoundSubject instead.
+// When the subject is a HistorySubject, its history is committed as well.
 func (sm *StateMachine) TriggerSubject(subject Subject, event string, args ...interface{}) error {
-	args = append([]interface{}{subject}, args...)
+	return sm.TriggerSubjectContext(context.Background(), subject, event, args...)
+}
+
+// TriggerSubjectContext triggers an event with a context using the Subject's current state.
+//
+// See TriggerSubject for details.
+func (sm *StateMachine) TriggerSubjectContext(ctx context.Context, subject Subject, event string, args ...interface{}) error {
+	args = prependSubject(subject, args)
+
+	history := sm.subjectHistory(subject)
+
+	currentState := subject.GetState()
+
+	// The version has to be read before anything happens
+	vSubject, versioned := subject.(VersionedSubject)
+
+	var version int64
+	if versioned {
+		version = vSubject.GetVersion()
+	}
+
+	states, err := sm.trigger(ctx, subject, []string{currentState}, event, args, history, true)
+	if err != nil {
+		return err
+	}
+
+	if versioned {
+		if err := sm.compareAndSwap(vSubject, version, currentState, states[0], event, args); err != nil {
+			return err
+		}
+
+		sm.commitHistory(subject, history)
+
+		return nil
+	}
+
+	sm.commitHistory(subject, history)
+
+	if mSubject, ok := subject.(MutableSubject); ok && states[0] != currentState {
+		mSubject.SetState(states[0])
+	}
+
+	return nil
+}
+
+// prependSubject returns the arguments prefixed by the subject using a single allocation.
+func prependSubject(subject interface{}, args []interface{}) []interface{} {
+	subjectArgs := make([]interface{}, len(args)+1)
+	subjectArgs[0] = subject
+	copy(subjectArgs[1:], args)
 
-	return sm.Trigger(subject.GetState(), event, args...)
+	return subjectArgs
 }
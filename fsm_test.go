@@ -1,9 +1,10 @@
 package fsm_test
 
 import (
-	"testing"
-
+	"context"
 	"errors"
+	"fmt"
+	"testing"
 
 	"github.com/goph/fsm"
 	"github.com/goph/fsm/internal/mocks"
@@ -130,6 +131,9 @@ func TestStateMachine_InvalidTransition(t *testing.T) {
 	assert.Equal(t, "other_current_state", ierr.CurrentState())
 	assert.Equal(t, "event", ierr.Event())
 	assert.Equal(t, []interface{}{"argument"}, ierr.Arguments())
+	assert.Equal(t, fsm.CodeInvalidTransition, ierr.Code())
+	assert.True(t, errors.Is(err, fsm.ErrInvalidTransition))
+	assert.False(t, errors.Is(err, fsm.ErrGuardRejected))
 
 	delegate.AssertNotCalled(t, "Handle", "action", "current_state", "next_state", []interface{}{"argument"})
 }
@@ -195,10 +199,91 @@ func TestStateMachine_DelegateError(t *testing.T) {
 	assert.Equal(t, "current_state", derr.CurrentState())
 	assert.Equal(t, "event", derr.Event())
 	assert.Equal(t, []interface{}{"argument"}, derr.Arguments())
+	assert.Equal(t, fsm.CodeDelegateFailed, derr.Code())
+	assert.True(t, errors.Is(err, fsm.ErrDelegateFailed))
+	assert.True(t, errors.Is(err, delegateErr))
 
 	delegate.AssertExpectations(t)
 }
 
+func TestStateMachine_TransitionError(t *testing.T) {
+	transitions := []fsm.Transition{
+		{
+			FromState: "current_state",
+			Event:     "event",
+			ToState:   "next_state",
+		},
+	}
+
+	sm := fsm.NewStateMachine(new(mocks.Delegate), transitions)
+
+	err := fmt.Errorf("wrapped: %w", sm.Trigger("other_current_state", "event", "argument"))
+
+	var terr fsm.TransitionError
+	require.True(t, errors.As(err, &terr))
+
+	assert.Equal(t, "other_current_state", terr.CurrentState())
+	assert.Equal(t, "event", terr.Event())
+	assert.Equal(t, []interface{}{"argument"}, terr.Arguments())
+	assert.Equal(t, fsm.CodeInvalidTransition, terr.Code())
+	assert.True(t, errors.Is(err, fsm.ErrInvalidTransition))
+}
+
+type customDelegateError struct {
+	reason string
+}
+
+func (e *customDelegateError) Error() string {
+	return e.reason
+}
+
+func TestStateMachine_DelegateError_As(t *testing.T) {
+	delegate := new(mocks.Delegate)
+	transitions := []fsm.Transition{
+		{
+			FromState: "current_state",
+			Event:     "event",
+			ToState:   "next_state",
+			Action:    "action",
+		},
+	}
+
+	delegate.
+		On("Handle", "action", "current_state", "next_state", []interface{}(nil)).
+		Return(fmt.Errorf("action failed: %w", &customDelegateError{"insufficient funds"}))
+
+	sm := fsm.NewStateMachine(delegate, transitions)
+
+	err := sm.Trigger("current_state", "event")
+
+	var cerr *customDelegateError
+	require.True(t, errors.As(err, &cerr))
+
+	assert.Equal(t, "insufficient funds", cerr.reason)
+}
+
+func TestStateMachine_WrappedStopPropagation(t *testing.T) {
+	delegate := new(mocks.Delegate)
+	transitions := []fsm.Transition{
+		{
+			FromState: "current_state",
+			Event:     "event",
+			ToState:   "next_state",
+			Action:    "action",
+		},
+	}
+
+	delegate.
+		On("Handle", "action", "current_state", "next_state", []interface{}(nil)).
+		Return(fmt.Errorf("done: %w", fsm.StopPropagation))
+
+	sm := fsm.NewStateMachine(delegate, transitions)
+
+	err := sm.Trigger("current_state", "event")
+
+	assert.NoError(t, err)
+}
+
 func TestStateMachine_StopPropagation(t *testing.T) {
 	delegate := new(mocks.Delegate)
 	transitions := []fsm.Transition{
@@ -220,3 +305,318 @@ func TestStateMachine_StopPropagation(t *testing.T) {
 
 	delegate.AssertExpectations(t)
 }
+
+func TestStateMachine_MultipleActions(t *testing.T) {
+	delegate := new(mocks.Delegate)
+	transitions := []fsm.Transition{
+		{
+			FromState: "current_state",
+			Event:     "event",
+			ToState:   "next_state",
+			Action:    "charge_card",
+			Actions:   []string{"send_receipt", "notify_warehouse"},
+		},
+	}
+
+	calls := new(recorder)
+
+	delegate.On("Handle", mock.Anything, "current_state", "next_state", []interface{}{"argument"}).Return(calls.record)
+
+	sm := fsm.NewStateMachine(delegate, transitions)
+
+	err := sm.Trigger("current_state", "event", "argument")
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"charge_card", "send_receipt", "notify_warehouse"}, calls.actions)
+}
+
+func TestStateMachine_MultipleActions_StopPropagation(t *testing.T) {
+	delegate := new(mocks.Delegate)
+	transitions := []fsm.Transition{
+		{
+			FromState: "current_state",
+			Event:     "event",
+			ToState:   "next_state",
+			Action:    "charge_card",
+			Actions:   []string{"send_receipt", "notify_warehouse"},
+		},
+	}
+	states := []fsm.State{
+		{
+			Name:    "next_state",
+			OnEnter: "enter_next_state",
+		},
+	}
+
+	subject := new(mocks.MutableSubject)
+
+	subject.On("GetState").Return("current_state")
+	subject.On("SetState", "next_state").Return()
+
+	args := []interface{}{subject, "argument"}
+
+	delegate.On("Handle", "charge_card", "current_state", "next_state", args).Return(nil)
+	delegate.On("Handle", "send_receipt", "current_state", "next_state", args).Return(fsm.StopPropagation)
+	delegate.On("Handle", "enter_next_state", "current_state", "next_state", args).Return(nil)
+
+	sm := fsm.NewStateMachine(delegate, transitions, fsm.WithStates(states))
+
+	err := sm.TriggerSubject(subject, "event", "argument")
+
+	require.NoError(t, err)
+
+	// The remaining actions are skipped, but the state is entered and committed
+	delegate.AssertExpectations(t)
+	delegate.AssertNotCalled(t, "Handle", "notify_warehouse", "current_state", "next_state", args)
+	subject.AssertExpectations(t)
+}
+
+func TestStateMachine_MultipleActions_Error(t *testing.T) {
+	delegate := new(mocks.Delegate)
+	transitions := []fsm.Transition{
+		{
+			FromState: "current_state",
+			Event:     "event",
+			ToState:   "next_state",
+			Action:    "charge_card",
+			Actions:   []string{"send_receipt", "notify_warehouse"},
+		},
+	}
+
+	delegateErr := errors.New("error happened")
+
+	delegate.On("Handle", "charge_card", "current_state", "next_state", []interface{}{"argument"}).Return(nil)
+	delegate.On("Handle", "send_receipt", "current_state", "next_state", []interface{}{"argument"}).Return(delegateErr)
+
+	sm := fsm.NewStateMachine(delegate, transitions)
+
+	err := sm.Trigger("current_state", "event", "argument")
+
+	require.Error(t, err)
+
+	derr := err.(*fsm.DelegateError)
+
+	assert.Equal(t, "send_receipt", derr.Action())
+	assert.Equal(t, delegateErr, derr.Cause())
+
+	delegate.AssertNotCalled(t, "Handle", "notify_warehouse", "current_state", "next_state", []interface{}{"argument"})
+}
+
+func TestStateMachine_Guard(t *testing.T) {
+	delegate := new(mocks.Delegate)
+	guard := new(mocks.Guard)
+	transitions := []fsm.Transition{
+		{
+			FromState: "current_state",
+			Event:     "event",
+			ToState:   "next_state",
+			Action:    "action",
+			Guard:     "guard",
+		},
+		{
+			FromState: "current_state",
+			Event:     "event",
+			ToState:   "other_next_state",
+			Action:    "other_action",
+			Guard:     "other_guard",
+		},
+	}
+
+	guard.On("Check", "guard", "current_state", "next_state", []interface{}{"argument"}).Return(false)
+	guard.On("Check", "other_guard", "current_state", "other_next_state", []interface{}{"argument"}).Return(true)
+	delegate.On("Handle", "other_action", "current_state", "other_next_state", []interface{}{"argument"}).Return(nil)
+
+	sm := fsm.NewStateMachine(delegate, transitions, fsm.WithGuard(guard))
+
+	err := sm.Trigger("current_state", "event", "argument")
+
+	require.NoError(t, err)
+
+	guard.AssertExpectations(t)
+	delegate.AssertExpectations(t)
+}
+
+func TestStateMachine_GuardRejected(t *testing.T) {
+	delegate := new(mocks.Delegate)
+	guard := new(mocks.Guard)
+	transitions := []fsm.Transition{
+		{
+			FromState: "current_state",
+			Event:     "event",
+			ToState:   "next_state",
+			Action:    "action",
+			Guard:     "guard",
+		},
+	}
+
+	guard.On("Check", "guard", "current_state", "next_state", []interface{}{"argument"}).Return(false)
+
+	sm := fsm.NewStateMachine(delegate, transitions, fsm.WithGuard(guard))
+
+	err := sm.Trigger("current_state", "event", "argument")
+
+	require.Error(t, err)
+
+	gerr := err.(*fsm.GuardRejectedError)
+
+	assert.EqualError(t, gerr, "guards rejected every transition from \"current_state\" state triggered by \"event\" event")
+	assert.Equal(t, "current_state", gerr.CurrentState())
+	assert.Equal(t, "event", gerr.Event())
+	assert.Equal(t, []interface{}{"argument"}, gerr.Arguments())
+	assert.Equal(t, fsm.CodeGuardRejected, gerr.Code())
+	assert.True(t, errors.Is(err, fsm.ErrGuardRejected))
+
+	delegate.AssertNotCalled(t, "Handle", "action", "current_state", "next_state", []interface{}{"argument"})
+}
+
+func TestStateMachine_GuardWithoutGuard(t *testing.T) {
+	delegate := new(mocks.Delegate)
+	transitions := []fsm.Transition{
+		{
+			FromState: "current_state",
+			Event:     "event",
+			ToState:   "next_state",
+			Action:    "action",
+			Guard:     "guard",
+		},
+	}
+
+	sm := fsm.NewStateMachine(delegate, transitions)
+
+	err := sm.Trigger("current_state", "event", "argument")
+
+	require.Error(t, err)
+	assert.IsType(t, &fsm.GuardRejectedError{}, err)
+}
+
+type contextKey string
+
+func TestStateMachine_TriggerContext(t *testing.T) {
+	delegate := new(mocks.ContextDelegate)
+	transitions := []fsm.Transition{
+		{
+			FromState: "current_state",
+			Event:     "event",
+			ToState:   "next_state",
+			Action:    "action",
+		},
+	}
+
+	ctx := context.WithValue(context.Background(), contextKey("key"), "value")
+
+	delegate.On("HandleContext", ctx, "action", "current_state", "next_state", []interface{}{"argument"}).Return(nil)
+
+	sm := fsm.NewStateMachine(fsm.NewContextDelegateAdapter(delegate), transitions)
+
+	err := sm.TriggerContext(ctx, "current_state", "event", "argument")
+
+	require.NoError(t, err)
+
+	delegate.AssertExpectations(t)
+}
+
+func TestStateMachine_TriggerContext_Canceled(t *testing.T) {
+	delegate := new(mocks.ContextDelegate)
+	transitions := []fsm.Transition{
+		{
+			FromState: "current_state",
+			Event:     "event",
+			ToState:   "next_state",
+			Action:    "action",
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	sm := fsm.NewStateMachine(fsm.NewContextDelegateAdapter(delegate), transitions)
+
+	err := sm.TriggerContext(ctx, "current_state", "event", "argument")
+
+	assert.Equal(t, context.Canceled, err)
+
+	delegate.AssertNotCalled(t, "HandleContext", ctx, "action", "current_state", "next_state", []interface{}{"argument"})
+}
+
+func TestStateMachine_TriggerSubjectContext(t *testing.T) {
+	delegate := new(mocks.ContextDelegate)
+	transitions := []fsm.Transition{
+		{
+			FromState: "current_state",
+			Event:     "event",
+			ToState:   "next_state",
+			Action:    "action",
+		},
+	}
+
+	subject := new(mocks.Subject)
+
+	subject.On("GetState").Return("current_state")
+
+	ctx := context.WithValue(context.Background(), contextKey("key"), "value")
+
+	delegate.On("HandleContext", ctx, "action", "current_state", "next_state", []interface{}{subject, "argument"}).Return(nil)
+
+	sm := fsm.NewStateMachine(fsm.NewContextDelegateAdapter(delegate), transitions)
+
+	err := sm.TriggerSubjectContext(ctx, subject, "event", "argument")
+
+	require.NoError(t, err)
+
+	delegate.AssertExpectations(t)
+	subject.AssertExpectations(t)
+}
+
+func TestStateMachine_MutableSubject(t *testing.T) {
+	delegate := new(mocks.Delegate)
+	transitions := []fsm.Transition{
+		{
+			FromState: "current_state",
+			Event:     "event",
+			ToState:   "next_state",
+			Action:    "action",
+		},
+	}
+
+	subject := new(mocks.MutableSubject)
+
+	subject.On("GetState").Return("current_state")
+	subject.On("SetState", "next_state")
+
+	delegate.On("Handle", "action", "current_state", "next_state", []interface{}{subject, "argument"}).Return(nil)
+
+	sm := fsm.NewStateMachine(delegate, transitions)
+
+	err := sm.TriggerSubject(subject, "event", "argument")
+
+	require.NoError(t, err)
+
+	delegate.AssertExpectations(t)
+	subject.AssertExpectations(t)
+}
+
+func TestStateMachine_MutableSubject_DelegateError(t *testing.T) {
+	delegate := new(mocks.Delegate)
+	transitions := []fsm.Transition{
+		{
+			FromState: "current_state",
+			Event:     "event",
+			ToState:   "next_state",
+			Action:    "action",
+		},
+	}
+
+	subject := new(mocks.MutableSubject)
+
+	subject.On("GetState").Return("current_state")
+
+	delegate.On("Handle", "action", "current_state", "next_state", []interface{}{subject, "argument"}).Return(errors.New("error happened"))
+
+	sm := fsm.NewStateMachine(delegate, transitions)
+
+	err := sm.TriggerSubject(subject, "event", "argument")
+
+	require.Error(t, err)
+
+	subject.AssertNotCalled(t, "SetState", "next_state")
+}
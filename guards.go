@@ -0,0 +1,48 @@
+package fsm
+
+import "sort"
+
+// Guard is responsible for deciding whether a guarded transition can be selected.
+type Guard interface {
+	// Check returns true if the transition is allowed to happen.
+	//
+	// A guard must not have any side effects:
+	// it might be called for transitions that are not selected in the end.
+	//
+	// The from state is the current (innermost) state, just like the one passed to the delegate,
+	// even if the transition is declared on an ancestor or it is a wildcard transition.
+	Check(guard string, fromState string, toState string, args []interface{}) bool
+}
+
+// GuardMux allows to register a guard per guard name.
+type GuardMux struct {
+	guards map[string]Guard
+}
+
+// NewGuardMux returns a new GuardMux.
+func NewGuardMux(guards map[string]Guard) *GuardMux {
+	return &GuardMux{guards}
+}
+
+// Check calls the underlying guard for a guard name.
+//
+// Unknown guards always reject the transition.
+func (g *GuardMux) Check(guard string, fromState string, toState string, args []interface{}) bool {
+	if gd, ok := g.guards[guard]; ok {
+		return gd.Check(guard, fromState, toState, args)
+	}
+
+	return false
+}
+
+// Guards returns the names of the guards in alphabetical order.
+func (g *GuardMux) Guards() []string {
+	guards := make([]string, 0, len(g.guards))
+	for guard := range g.guards {
+		guards = append(guards, guard)
+	}
+
+	sort.Strings(guards)
+
+	return guards
+}
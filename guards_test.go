@@ -0,0 +1,49 @@
+package fsm_test
+
+import (
+	"testing"
+
+	"github.com/goph/fsm"
+	"github.com/goph/fsm/internal/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGuardMux(t *testing.T) {
+	guard := new(mocks.Guard)
+	guard.On("Check", "guard", "fromState", "toState", []interface{}{"argument"}).Return(true)
+
+	guards := map[string]fsm.Guard{
+		"guard": guard,
+	}
+
+	gm := fsm.NewGuardMux(guards)
+
+	assert.True(t, gm.Check("guard", "fromState", "toState", []interface{}{"argument"}))
+
+	guard.AssertExpectations(t)
+}
+
+func TestGuardMux_UnknownGuard(t *testing.T) {
+	guard := new(mocks.Guard)
+
+	guards := map[string]fsm.Guard{
+		"guard": guard,
+	}
+
+	gm := fsm.NewGuardMux(guards)
+
+	assert.False(t, gm.Check("other_guard", "fromState", "toState", []interface{}{"argument"}))
+
+	guard.AssertNotCalled(t, "Check", "guard", "fromState", "toState", []interface{}{"argument"})
+}
+
+func TestGuardMux_Guards(t *testing.T) {
+	guards := map[string]fsm.Guard{
+		"guard2": new(mocks.Guard),
+		"guard1": new(mocks.Guard),
+	}
+
+	gm := fsm.NewGuardMux(guards)
+
+	assert.Equal(t, []string{"guard1", "guard2"}, gm.Guards())
+}
@@ -0,0 +1,224 @@
+package fsm
+
+// hierarchy contains the declared states and answers questions about their hierarchy.
+//
+// Invalid hierarchies (eg. parent cycles) are reported by Validate, but they must not hang the state machine:
+// walks up and down the hierarchy are limited by the number of states.
+type hierarchy struct {
+	states map[string]State
+
+	// children contains the child states of composite states (except history pseudo-states) in declaration order.
+	children map[string][]string
+
+	// duplicates contains the states declared more than once.
+	duplicates map[string]bool
+}
+
+// newHierarchy returns the hierarchy of states.
+func newHierarchy(states []State) hierarchy {
+	h := hierarchy{
+		states:     make(map[string]State, len(states)),
+		children:   make(map[string][]string),
+		duplicates: make(map[string]bool),
+	}
+
+	for _, state := range states {
+		h.add(state)
+	}
+
+	return h
+}
+
+// add declares a state.
+//
+// The last declaration of a state wins, but it keeps its position among its siblings.
+func (h *hierarchy) add(state State) {
+	previous, declared := h.states[state.Name]
+	h.states[state.Name] = state
+
+	if declared {
+		h.duplicates[state.Name] = true
+
+		if isChild(previous) && isChild(state) && previous.Parent == state.Parent {
+			return
+		}
+
+		if isChild(previous) {
+			h.children[previous.Parent] = removeState(h.children[previous.Parent], state.Name)
+		}
+	}
+
+	if isChild(state) {
+		h.children[state.Parent] = append(h.children[state.Parent], state.Name)
+	}
+}
+
+// isChild checks whether a state is a child of a composite state.
+//
+// History pseudo-states are not regions of parallel states, so they are not considered children.
+func isChild(state State) bool {
+	return state.Parent != "" && state.History == ""
+}
+
+// removeState returns the states without a state.
+func removeState(states []string, state string) []string {
+	result := states[:0]
+
+	for _, s := range states {
+		if s != state {
+			result = append(result, s)
+		}
+	}
+
+	return result
+}
+
+// ancestors returns the state itself followed by its ancestors from the innermost to the outermost one.
+func (h *hierarchy) ancestors(state string) []string {
+	ancestors := []string{state}
+
+	for i := 0; i < len(h.states); i++ {
+		state = h.states[state].Parent
+		if state == "" {
+			break
+		}
+
+		ancestors = append(ancestors, state)
+	}
+
+	return ancestors
+}
+
+// isAncestor checks whether a state is a proper ancestor of another one.
+func (h *hierarchy) isAncestor(ancestor string, state string) bool {
+	for i := 0; i < len(h.states); i++ {
+		state = h.states[state].Parent
+		if state == "" {
+			return false
+		}
+
+		if state == ancestor {
+			return true
+		}
+	}
+
+	return false
+}
+
+// within checks whether a state is the same as or a descendant of another one.
+func (h *hierarchy) within(state string, ancestor string) bool {
+	return state == ancestor || h.isAncestor(ancestor, state)
+}
+
+// covers checks whether a state is one of the states or one of their ancestors.
+func (h *hierarchy) covers(states []string, state string) bool {
+	for _, s := range states {
+		if h.within(s, state) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// resolveTargets returns the leaf states entered when a transition targets a state.
+//
+// Targeting a parallel state enters every region, so it results in multiple leaf states.
+// Targeting a history pseudo-state enters the states recorded in the history (if any)
+// or its default state (the Initial state of the pseudo-state).
+// Without a default state the parent is entered as if it was targeted.
+func (h *hierarchy) resolveTargets(state string, history History) []string {
+	return h.appendTargets(nil, state, history, len(h.states))
+}
+
+// appendTargets appends the leaf states entered by targeting a state.
+func (h *hierarchy) appendTargets(targets []string, state string, history History, depth int) []string {
+	s := h.states[state]
+
+	switch {
+	case depth <= 0:
+		return append(targets, state)
+
+	case s.History != "":
+		recorded := history[s.Parent]
+
+		if len(recorded) == 0 {
+			if s.Initial == "" {
+				return h.appendTargets(targets, s.Parent, history, depth-1)
+			}
+
+			return h.appendTargets(targets, s.Initial, history, depth-1)
+		}
+
+		if s.History == DeepHistory {
+			return append(targets, recorded...)
+		}
+
+		var children []string
+
+		for _, leaf := range recorded {
+			child := h.childOf(s.Parent, leaf)
+			if !containsState(children, child) {
+				children = append(children, child)
+			}
+		}
+
+		for _, child := range children {
+			targets = h.appendTargets(targets, child, history, depth-1)
+		}
+
+		return targets
+
+	case s.Parallel:
+		for _, region := range h.children[state] {
+			targets = h.appendTargets(targets, region, history, depth-1)
+		}
+
+		return targets
+
+	case s.Initial != "":
+		return h.appendTargets(targets, s.Initial, history, depth-1)
+	}
+
+	return append(targets, state)
+}
+
+// childOf returns the child of a state which is an ancestor of (or the same as) a descendant state.
+func (h *hierarchy) childOf(state string, descendant string) string {
+	for _, s := range h.ancestors(descendant) {
+		if h.states[s].Parent == state {
+			return s
+		}
+	}
+
+	return descendant
+}
+
+// transitionDomain returns the innermost state which is a proper ancestor of both the source and the target state.
+//
+// An empty string means that the transition crosses the top level states.
+func (h *hierarchy) transitionDomain(source string, target string) string {
+	for i := 0; i < len(h.states); i++ {
+		source = h.states[source].Parent
+		if source == "" {
+			break
+		}
+
+		if h.isAncestor(source, target) {
+			return source
+		}
+	}
+
+	return ""
+}
+
+// excludes checks whether a wildcard transition cannot be triggered in a state.
+func (h *hierarchy) excludes(t *Transition, state string) bool {
+	for _, except := range t.ExceptStates {
+		if h.within(state, except) {
+			return true
+		}
+	}
+
+	return false
+}
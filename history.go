@@ -0,0 +1,88 @@
+package fsm
+
+// HistoryType is the type of a history pseudo-state.
+type HistoryType string
+
+const (
+	// ShallowHistory restores the direct child of the parent state,
+	// then enters its descendants using their initial states.
+	ShallowHistory HistoryType = "shallow"
+
+	// DeepHistory restores the leaf states which were active when the parent state was left.
+	DeepHistory HistoryType = "deep"
+)
+
+// History contains the leaf states which were active when composite states were left, keyed by the composite states.
+//
+// History is only recorded for states having a history pseudo-state.
+type History map[string][]string
+
+// HistorySubject is implemented by subjects storing the history recorded for them,
+// so that it can be persisted together with their state.
+//
+// The state machine reads the history before a transition
+// and sets the new history along with the new state (if the transition succeeded):
+// right before setting the state of mutable subjects and right after a successful compare-and-swap of versioned subjects.
+// History is not recorded for other subjects: history pseudo-states always enter their default state.
+type HistorySubject interface {
+	// GetHistory returns the history of the subject.
+	GetHistory() History
+
+	// SetHistory sets the history of the subject.
+	SetHistory(history History)
+}
+
+// subjectHistory returns a copy of the history of a subject which can be recorded during a transition.
+//
+// It returns nil if the subject doesn't store its history or none of the states has history,
+// so that history is not recorded unnecessarily.
+func (sm *StateMachine) subjectHistory(subject interface{}) History {
+	hSubject, ok := subject.(HistorySubject)
+	if !ok || len(sm.hasHistory) == 0 {
+		return nil
+	}
+
+	// Recorded leaves are replaced, never modified, so they can be shared
+	recorded := hSubject.GetHistory()
+
+	history := make(History, len(recorded))
+	for state, leaves := range recorded {
+		history[state] = leaves
+	}
+
+	return history
+}
+
+// commitHistory sets the history returned by subjectHistory in the subject.
+func (sm *StateMachine) commitHistory(subject interface{}, history History) {
+	if history == nil {
+		return
+	}
+
+	subject.(HistorySubject).SetHistory(history)
+}
+
+// recordHistory records the left leaf states for every left composite state having a history pseudo-state.
+func (sm *StateMachine) recordHistory(history History, exited []string, domain string) {
+	recorded := make(map[string]bool)
+
+	for _, leaf := range exited {
+		for _, state := range sm.ancestors(leaf)[1:] {
+			if state == domain {
+				break
+			}
+
+			if !sm.hasHistory[state] {
+				continue
+			}
+
+			// Previous history is overwritten once per transition
+			if !recorded[state] {
+				history[state] = nil
+				recorded[state] = true
+			}
+
+			history[state] = append(history[state], leaf)
+		}
+	}
+}
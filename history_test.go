@@ -0,0 +1,195 @@
+package fsm_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/goph/fsm"
+	"github.com/goph/fsm/internal/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// historyStates returns a composite state with nested children and a history pseudo-state.
+func historyStates(historyType fsm.HistoryType) []fsm.State {
+	return []fsm.State{
+		{
+			Name:    "in_fulfilment",
+			Initial: "picking",
+		},
+		{
+			Name:    "in_fulfilment.history",
+			Parent:  "in_fulfilment",
+			History: historyType,
+		},
+		{
+			Name:   "picking",
+			Parent: "in_fulfilment",
+		},
+		{
+			Name:    "packing",
+			Parent:  "in_fulfilment",
+			Initial: "boxing",
+		},
+		{
+			Name:   "boxing",
+			Parent: "packing",
+		},
+		{
+			Name:   "labelling",
+			Parent: "packing",
+		},
+	}
+}
+
+func historyTransitions() []fsm.Transition {
+	return []fsm.Transition{
+		{
+			FromState: "in_fulfilment",
+			Event:     "hold",
+			ToState:   "on_hold",
+			Action:    "hold",
+		},
+		{
+			FromState: "on_hold",
+			Event:     "resume",
+			ToState:   "in_fulfilment.history",
+			Action:    "resume",
+		},
+	}
+}
+
+// persistedOrder is a subject persisting its state and history.
+type persistedOrder struct {
+	state   string
+	history fsm.History
+}
+
+func (o *persistedOrder) GetState() string {
+	return o.state
+}
+
+func (o *persistedOrder) SetState(state string) {
+	o.state = state
+}
+
+func (o *persistedOrder) GetHistory() fsm.History {
+	return o.history
+}
+
+func (o *persistedOrder) SetHistory(history fsm.History) {
+	o.history = history
+}
+
+func TestStateMachine_ShallowHistory(t *testing.T) {
+	delegate := new(mocks.Delegate)
+	subject := &persistedOrder{state: "labelling"}
+
+	delegate.On("Handle", "hold", "labelling", "on_hold", []interface{}{subject}).Return(nil)
+	delegate.On("Handle", "resume", "on_hold", "boxing", []interface{}{subject}).Return(nil)
+
+	sm := fsm.NewStateMachine(delegate, historyTransitions(), fsm.WithStates(historyStates(fsm.ShallowHistory)))
+
+	require.NoError(t, sm.TriggerSubject(subject, "hold"))
+	require.NoError(t, sm.TriggerSubject(subject, "resume"))
+
+	assert.Equal(t, "boxing", subject.state)
+
+	delegate.AssertExpectations(t)
+}
+
+func TestStateMachine_DeepHistory(t *testing.T) {
+	delegate := new(mocks.Delegate)
+	subject := &persistedOrder{state: "labelling"}
+
+	delegate.On("Handle", "hold", "labelling", "on_hold", []interface{}{subject}).Return(nil)
+	delegate.On("Handle", "resume", "on_hold", "labelling", []interface{}{subject}).Return(nil)
+
+	sm := fsm.NewStateMachine(delegate, historyTransitions(), fsm.WithStates(historyStates(fsm.DeepHistory)))
+
+	require.NoError(t, sm.TriggerSubject(subject, "hold"))
+	require.NoError(t, sm.TriggerSubject(subject, "resume"))
+
+	assert.Equal(t, "labelling", subject.state)
+
+	delegate.AssertExpectations(t)
+}
+
+func TestStateMachine_HistoryDefault(t *testing.T) {
+	delegate := new(mocks.Delegate)
+	subject := new(mocks.Subject)
+
+	subject.On("GetState").Return("on_hold")
+
+	delegate.On("Handle", "resume", "on_hold", "picking", []interface{}{subject}).Return(nil)
+
+	sm := fsm.NewStateMachine(delegate, historyTransitions(), fsm.WithStates(historyStates(fsm.DeepHistory)))
+
+	require.NoError(t, sm.TriggerSubject(subject, "resume"))
+
+	delegate.AssertExpectations(t)
+}
+
+func TestStateMachine_PersistedHistory(t *testing.T) {
+	delegate := new(mocks.Delegate)
+	subject := &persistedOrder{state: "labelling"}
+
+	delegate.On("Handle", "hold", "labelling", "on_hold", []interface{}{subject}).Return(nil)
+
+	sm := fsm.NewStateMachine(delegate, historyTransitions(), fsm.WithStates(historyStates(fsm.DeepHistory)))
+
+	require.NoError(t, sm.TriggerSubject(subject, "hold"))
+
+	assert.Equal(t, fsm.History{"in_fulfilment": {"labelling"}}, subject.history)
+
+	// The subject is loaded again (eg. from a database) and triggered by an other state machine
+	reloaded := &persistedOrder{state: subject.state, history: subject.history}
+
+	otherDelegate := new(mocks.Delegate)
+	otherDelegate.On("Handle", "resume", "on_hold", "labelling", []interface{}{reloaded}).Return(nil)
+
+	otherSm := fsm.NewStateMachine(otherDelegate, historyTransitions(), fsm.WithStates(historyStates(fsm.DeepHistory)))
+
+	require.NoError(t, otherSm.TriggerSubject(reloaded, "resume"))
+
+	assert.Equal(t, "labelling", reloaded.state)
+
+	otherDelegate.AssertExpectations(t)
+}
+
+func TestStateMachine_History_DelegateError(t *testing.T) {
+	delegate := new(mocks.Delegate)
+	subject := &persistedOrder{state: "labelling"}
+
+	delegate.On("Handle", "hold", "labelling", "on_hold", []interface{}{subject}).Return(errors.New("error happened"))
+
+	sm := fsm.NewStateMachine(delegate, historyTransitions(), fsm.WithStates(historyStates(fsm.DeepHistory)))
+
+	require.Error(t, sm.TriggerSubject(subject, "hold"))
+
+	assert.Equal(t, "labelling", subject.state)
+	assert.Nil(t, subject.history)
+}
+
+// taggedOrder is a subject which cannot be compared (eg. used as a map key).
+type taggedOrder struct {
+	state string
+	tags  []string
+}
+
+func (o taggedOrder) GetState() string {
+	return o.state
+}
+
+func TestStateMachine_History_UncomparableSubject(t *testing.T) {
+	delegate := new(mocks.Delegate)
+	subject := taggedOrder{state: "labelling", tags: []string{"express"}}
+
+	delegate.On("Handle", "hold", "labelling", "on_hold", []interface{}{subject}).Return(nil)
+
+	sm := fsm.NewStateMachine(delegate, historyTransitions(), fsm.WithStates(historyStates(fsm.DeepHistory)))
+
+	require.NoError(t, sm.TriggerSubject(subject, "hold"))
+
+	delegate.AssertExpectations(t)
+}
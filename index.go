@@ -0,0 +1,124 @@
+package fsm
+
+// transitionKey identifies the transitions of a state-event pair in the index.
+type transitionKey struct {
+	fromState string
+	event     string
+}
+
+// compiledTransition contains a transition and everything about it which can be computed in advance.
+type compiledTransition struct {
+	transition *Transition
+
+	// domain is the innermost state which is not left during the transition.
+	domain string
+
+	// targets are the leaf states entered during the transition.
+	targets []string
+
+	// dynamic is true when the targets depend on history, so they have to be resolved on every trigger.
+	dynamic bool
+
+	// wildcard is true for transitions from AnyState: their domain depends on the current state.
+	wildcard bool
+}
+
+// compile builds the transition index.
+//
+// Transitions with multiple source states are indexed for each of them,
+// wildcard transitions are indexed by their event only.
+// It has to be called after the states are declared.
+func (sm *StateMachine) compile() {
+	sm.index = make(map[transitionKey][]*compiledTransition, len(sm.transitions))
+	sm.wildcards = make(map[string][]*compiledTransition)
+
+	// Transitions targeting the same state share their targets
+	targets := make(map[string][]string)
+
+	for i := range sm.transitions {
+		t := &sm.transitions[i]
+
+		ct := compiledTransition{
+			transition: t,
+			dynamic:    sm.isDynamicTarget(t.ToState, len(sm.states)),
+			wildcard:   t.FromState == AnyState,
+		}
+
+		if !ct.dynamic {
+			if _, ok := targets[t.ToState]; !ok {
+				targets[t.ToState] = sm.resolveTargets(t.ToState, nil)
+			}
+
+			ct.targets = targets[t.ToState]
+		}
+
+		if ct.wildcard {
+			sm.wildcards[t.Event] = append(sm.wildcards[t.Event], &ct)
+
+			continue
+		}
+
+		for _, source := range sources(t) {
+			sct := ct
+			sct.domain = sm.transitionDomain(source, t.ToState)
+
+			key := transitionKey{source, t.Event}
+			sm.index[key] = append(sm.index[key], &sct)
+		}
+	}
+}
+
+// findTransitions returns every transition declared for the state-event pair in declaration order.
+func (sm *StateMachine) findTransitions(fromState string, event string) []*compiledTransition {
+	return sm.index[transitionKey{fromState, event}]
+}
+
+// targets returns the leaf states entered by a transition.
+func (sm *StateMachine) targets(ct *compiledTransition, history History) []string {
+	if ct.dynamic {
+		return sm.resolveTargets(ct.transition.ToState, history)
+	}
+
+	return ct.targets
+}
+
+// sources returns the explicit source states of a transition.
+func sources(t *Transition) []string {
+	if t.FromState == "" {
+		return t.FromStates
+	}
+
+	return append([]string{t.FromState}, t.FromStates...)
+}
+
+// hasSource checks whether a state is an explicit source state of a transition.
+func hasSource(t *Transition, state string) bool {
+	return t.FromState == state || containsState(t.FromStates, state)
+}
+
+// isDynamicTarget checks whether entering a state depends on history.
+func (sm *StateMachine) isDynamicTarget(state string, depth int) bool {
+	s := sm.states[state]
+
+	switch {
+	case depth <= 0:
+		return false
+
+	case s.History != "":
+		return true
+
+	case s.Parallel:
+		for _, region := range sm.children[state] {
+			if sm.isDynamicTarget(region, depth-1) {
+				return true
+			}
+		}
+
+		return false
+
+	case s.Initial != "":
+		return sm.isDynamicTarget(s.Initial, depth-1)
+	}
+
+	return false
+}
@@ -0,0 +1,25 @@
+// Code generated by mockery v1.0.0
+package mocks
+
+import mock "github.com/stretchr/testify/mock"
+
+// CompoundSubject is an autogenerated mock type for the CompoundSubject type
+type CompoundSubject struct {
+	mock.Mock
+}
+
+// GetStates provides a mock function with given fields:
+func (_m *CompoundSubject) GetStates() []string {
+	ret := _m.Called()
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func() []string); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	return r0
+}
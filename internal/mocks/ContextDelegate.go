@@ -0,0 +1,24 @@
+// Code generated by mockery v1.0.0
+package mocks
+
+import context "context"
+import mock "github.com/stretchr/testify/mock"
+
+// ContextDelegate is an autogenerated mock type for the ContextDelegate type
+type ContextDelegate struct {
+	mock.Mock
+}
+
+// HandleContext provides a mock function with given fields: ctx, action, fromState, toState, args
+func (_m *ContextDelegate) HandleContext(ctx context.Context, action string, fromState string, toState string, args []interface{}) error {
+	ret := _m.Called(ctx, action, fromState, toState, args)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, []interface{}) error); ok {
+		r0 = rf(ctx, action, fromState, toState, args)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
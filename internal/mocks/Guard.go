@@ -0,0 +1,23 @@
+// Code generated by mockery v1.0.0
+package mocks
+
+import mock "github.com/stretchr/testify/mock"
+
+// Guard is an autogenerated mock type for the Guard type
+type Guard struct {
+	mock.Mock
+}
+
+// Check provides a mock function with given fields: guard, fromState, toState, args
+func (_m *Guard) Check(guard string, fromState string, toState string, args []interface{}) bool {
+	ret := _m.Called(guard, fromState, toState, args)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(string, string, string, []interface{}) bool); ok {
+		r0 = rf(guard, fromState, toState, args)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
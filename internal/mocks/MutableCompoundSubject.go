@@ -0,0 +1,30 @@
+// Code generated by mockery v1.0.0
+package mocks
+
+import mock "github.com/stretchr/testify/mock"
+
+// MutableCompoundSubject is an autogenerated mock type for the MutableCompoundSubject type
+type MutableCompoundSubject struct {
+	mock.Mock
+}
+
+// GetStates provides a mock function with given fields:
+func (_m *MutableCompoundSubject) GetStates() []string {
+	ret := _m.Called()
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func() []string); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	return r0
+}
+
+// SetStates provides a mock function with given fields: states
+func (_m *MutableCompoundSubject) SetStates(states []string) {
+	_m.Called(states)
+}
@@ -0,0 +1,28 @@
+// Code generated by mockery v1.0.0
+package mocks
+
+import mock "github.com/stretchr/testify/mock"
+
+// MutableSubject is an autogenerated mock type for the MutableSubject type
+type MutableSubject struct {
+	mock.Mock
+}
+
+// GetState provides a mock function with given fields:
+func (_m *MutableSubject) GetState() string {
+	ret := _m.Called()
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
+// SetState provides a mock function with given fields: state
+func (_m *MutableSubject) SetState(state string) {
+	_m.Called(state)
+}
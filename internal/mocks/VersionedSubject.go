@@ -0,0 +1,58 @@
+// Code generated by mockery v1.0.0
+package mocks
+
+import mock "github.com/stretchr/testify/mock"
+
+// VersionedSubject is an autogenerated mock type for the VersionedSubject type
+type VersionedSubject struct {
+	mock.Mock
+}
+
+// CompareAndSwapState provides a mock function with given fields: version, state
+func (_m *VersionedSubject) CompareAndSwapState(version int64, state string) (bool, error) {
+	ret := _m.Called(version, state)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(int64, string) bool); ok {
+		r0 = rf(version, state)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(int64, string) error); ok {
+		r1 = rf(version, state)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetState provides a mock function with given fields:
+func (_m *VersionedSubject) GetState() string {
+	ret := _m.Called()
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
+// GetVersion provides a mock function with given fields:
+func (_m *VersionedSubject) GetVersion() int64 {
+	ret := _m.Called()
+
+	var r0 int64
+	if rf, ok := ret.Get(0).(func() int64); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	return r0
+}
@@ -0,0 +1,68 @@
+package fsm
+
+// Transitions returns a copy of the transitions of the state machine in declaration order.
+func (sm *StateMachine) Transitions() []Transition {
+	return append([]Transition(nil), sm.transitions...)
+}
+
+// DryRun returns the transition which would fire if the event was triggered in the current state.
+//
+// Guards are evaluated with the arguments (prepend the subject to match TriggerSubject),
+// but no actions are executed and nothing is committed.
+// The errors are the same as the ones returned by Trigger before executing the transition.
+func (sm *StateMachine) DryRun(currentState string, event string, args ...interface{}) (Transition, error) {
+	ct, err := sm.resolveTransition(currentState, event, args)
+	if err != nil {
+		return Transition{}, err
+	}
+
+	return *ct.transition, nil
+}
+
+// Can checks whether an event can be triggered in the current state.
+//
+// See DryRun for details.
+func (sm *StateMachine) Can(currentState string, event string, args ...interface{}) bool {
+	_, err := sm.resolveTransition(currentState, event, args)
+
+	return err == nil
+}
+
+// AvailableEvents returns the events which can be triggered in the current state.
+//
+// Events of inner states come first (just like they take precedence), then the events of wildcard transitions.
+// Otherwise the events are in declaration order.
+// Guards are evaluated with the arguments, see DryRun for details.
+func (sm *StateMachine) AvailableEvents(currentState string, args ...interface{}) []string {
+	var events []string
+
+	seen := make(map[string]bool)
+
+	add := func(event string) {
+		if seen[event] {
+			return
+		}
+
+		seen[event] = true
+
+		if sm.Can(currentState, event, args...) {
+			events = append(events, event)
+		}
+	}
+
+	for _, state := range sm.ancestors(currentState) {
+		for i := range sm.transitions {
+			if t := &sm.transitions[i]; t.FromState != AnyState && hasSource(t, state) {
+				add(t.Event)
+			}
+		}
+	}
+
+	for i := range sm.transitions {
+		if t := &sm.transitions[i]; t.FromState == AnyState && !sm.excludes(t, currentState) {
+			add(t.Event)
+		}
+	}
+
+	return events
+}
@@ -0,0 +1,116 @@
+package fsm_test
+
+import (
+	"testing"
+
+	"github.com/goph/fsm"
+	"github.com/goph/fsm/internal/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func introspectionStateMachine(guard fsm.Guard) *fsm.StateMachine {
+	transitions := []fsm.Transition{
+		{
+			FromState: "picking",
+			Event:     "pick",
+			ToState:   "packing",
+			Action:    "pick",
+		},
+		{
+			FromState: "picking",
+			Event:     "cancel",
+			ToState:   "cancelled",
+			Action:    "cancel_picking",
+			Guard:     "picking_cancellable",
+		},
+		{
+			FromState: "in_fulfilment",
+			Event:     "cancel",
+			ToState:   "cancelled",
+			Action:    "cancel",
+			Guard:     "cancellable",
+		},
+		{
+			FromState: "in_fulfilment",
+			Event:     "hold",
+			ToState:   "on_hold",
+		},
+	}
+
+	// The delegate has no expectations: introspection must never execute actions
+	return fsm.NewStateMachine(new(mocks.Delegate), transitions, fsm.WithStates(orderStates()), fsm.WithGuard(guard))
+}
+
+func TestStateMachine_Transitions(t *testing.T) {
+	transitions := []fsm.Transition{
+		{
+			FromState: "current_state",
+			Event:     "event",
+			ToState:   "next_state",
+		},
+	}
+
+	sm := fsm.NewStateMachine(nil, transitions)
+
+	actual := sm.Transitions()
+	assert.Equal(t, transitions, actual)
+
+	// Modifying the result doesn't affect the state machine
+	actual[0].ToState = "other_state"
+	assert.Equal(t, transitions, sm.Transitions())
+}
+
+func TestStateMachine_DryRun(t *testing.T) {
+	guard := new(mocks.Guard)
+	guard.On("Check", "picking_cancellable", "picking", "cancelled", []interface{}{"argument"}).Return(false)
+	guard.On("Check", "cancellable", "picking", "cancelled", []interface{}{"argument"}).Return(true)
+
+	sm := introspectionStateMachine(guard)
+
+	transition, err := sm.DryRun("picking", "cancel", "argument")
+	require.NoError(t, err)
+
+	assert.Equal(t, sm.Transitions()[2], transition)
+	guard.AssertExpectations(t)
+}
+
+func TestStateMachine_DryRun_Errors(t *testing.T) {
+	guard := new(mocks.Guard)
+	guard.On("Check", "picking_cancellable", "picking", "cancelled", []interface{}(nil)).Return(false)
+	guard.On("Check", "cancellable", "picking", "cancelled", []interface{}(nil)).Return(false)
+
+	sm := introspectionStateMachine(guard)
+
+	_, err := sm.DryRun("picking", "cancel")
+	assert.IsType(t, &fsm.GuardRejectedError{}, err)
+
+	_, err = sm.DryRun("cancelled", "cancel")
+	assert.IsType(t, &fsm.InvalidTransitionError{}, err)
+}
+
+func TestStateMachine_Can(t *testing.T) {
+	guard := new(mocks.Guard)
+	guard.On("Check", "picking_cancellable", "picking", "cancelled", []interface{}(nil)).Return(false)
+	guard.On("Check", "cancellable", "picking", "cancelled", []interface{}(nil)).Return(false)
+
+	sm := introspectionStateMachine(guard)
+
+	assert.True(t, sm.Can("picking", "pick"))
+	assert.True(t, sm.Can("packing", "hold"))
+	assert.False(t, sm.Can("packing", "pick"))
+	assert.False(t, sm.Can("picking", "cancel"))
+}
+
+func TestStateMachine_AvailableEvents(t *testing.T) {
+	guard := new(mocks.Guard)
+	guard.On("Check", "picking_cancellable", "picking", "cancelled", []interface{}{"argument"}).Return(false)
+	guard.On("Check", "cancellable", "picking", "cancelled", []interface{}{"argument"}).Return(true)
+	guard.On("Check", "cancellable", "packing", "cancelled", []interface{}{"argument"}).Return(true)
+
+	sm := introspectionStateMachine(guard)
+
+	assert.Equal(t, []string{"pick", "cancel", "hold"}, sm.AvailableEvents("picking", "argument"))
+	assert.Equal(t, []string{"cancel", "hold"}, sm.AvailableEvents("packing", "argument"))
+	assert.Empty(t, sm.AvailableEvents("cancelled", "argument"))
+}
@@ -0,0 +1,93 @@
+package fsm
+
+import (
+	"context"
+	"time"
+)
+
+// Listener is notified about every transition attempt, independently of the delegate.
+//
+// Listeners are notified about invalid transitions (and rejected ones) as well as about delegate failures,
+// which makes them suitable for logging, auditing and metrics.
+// Listeners must not trigger the state machine.
+type Listener interface {
+	// BeforeTransition is called before the actions of a transition are executed.
+	//
+	// For invalid transitions it is called right before AfterTransition.
+	BeforeTransition(ctx context.Context, attempt Attempt)
+
+	// AfterTransition is called after the transition is executed (or found to be invalid).
+	AfterTransition(ctx context.Context, attempt Attempt, outcome Outcome)
+}
+
+// Attempt describes a transition attempt.
+type Attempt struct {
+	// Subject is the subject the event is triggered for or nil if the event is triggered for a state.
+	Subject interface{}
+
+	// CurrentState is the state (or the state of the region) the event is triggered in.
+	CurrentState string
+
+	Event string
+	Args  []interface{}
+
+	// Transition is a copy of the selected transition or nil if there is none
+	// (the transition is invalid or every guard rejected it).
+	Transition *Transition
+}
+
+// Outcome describes the outcome of a transition attempt.
+type Outcome struct {
+	// Err is the error of the transition or nil if it succeeded.
+	//
+	// Errors committing the state of the subject happen later, so they are not reported here.
+	Err error
+
+	// Duration is the time it took to execute the transition (including the delegate).
+	Duration time.Duration
+}
+
+// WithListener registers listeners notified about every transition attempt.
+func WithListener(listeners ...Listener) Option {
+	return func(sm *StateMachine) {
+		sm.listeners = append(sm.listeners, listeners...)
+	}
+}
+
+// notifyBefore notifies the listeners about a transition attempt and returns the time it started at.
+func (sm *StateMachine) notifyBefore(ctx context.Context, attempt Attempt) time.Time {
+	for _, listener := range sm.listeners {
+		listener.BeforeTransition(ctx, attempt)
+	}
+
+	return time.Now()
+}
+
+// notifyAfter notifies the listeners about the outcome of a transition attempt.
+func (sm *StateMachine) notifyAfter(ctx context.Context, attempt Attempt, start time.Time, err error) {
+	outcome := Outcome{
+		Err:      err,
+		Duration: time.Since(start),
+	}
+
+	for _, listener := range sm.listeners {
+		listener.AfterTransition(ctx, attempt, outcome)
+	}
+}
+
+// newAttempt returns the description of a transition attempt.
+func newAttempt(subject interface{}, currentState string, event string, args []interface{}, ct *compiledTransition) Attempt {
+	attempt := Attempt{
+		Subject:      subject,
+		CurrentState: currentState,
+		Event:        event,
+		Args:         args,
+	}
+
+	if ct != nil {
+		t := *ct.transition
+		attempt.Transition = &t
+	}
+
+	return attempt
+}
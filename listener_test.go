@@ -0,0 +1,171 @@
+package fsm_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/goph/fsm"
+	"github.com/goph/fsm/internal/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// notification is a call of a listener.
+type notification struct {
+	method  string
+	attempt fsm.Attempt
+	err     error
+}
+
+// recordingListener records the notifications of the listener and the calls of the delegate.
+type recordingListener struct {
+	notifications []notification
+}
+
+func (l *recordingListener) BeforeTransition(ctx context.Context, attempt fsm.Attempt) {
+	l.notifications = append(l.notifications, notification{"before", attempt, nil})
+}
+
+func (l *recordingListener) AfterTransition(ctx context.Context, attempt fsm.Attempt, outcome fsm.Outcome) {
+	l.notifications = append(l.notifications, notification{"after", attempt, outcome.Err})
+}
+
+func (l *recordingListener) Handle(action string, fromState string, toState string, args []interface{}) error {
+	l.notifications = append(l.notifications, notification{method: "handle " + action})
+
+	return nil
+}
+
+func TestStateMachine_Listener(t *testing.T) {
+	listener := new(recordingListener)
+	transitions := []fsm.Transition{
+		{
+			FromState: "current_state",
+			Event:     "event",
+			ToState:   "next_state",
+			Action:    "action",
+		},
+	}
+
+	sm := fsm.NewStateMachine(listener, transitions, fsm.WithListener(listener))
+
+	err := sm.Trigger("current_state", "event", "argument")
+	require.NoError(t, err)
+
+	attempt := fsm.Attempt{
+		CurrentState: "current_state",
+		Event:        "event",
+		Args:         []interface{}{"argument"},
+		Transition:   &transitions[0],
+	}
+
+	assert.Equal(
+		t,
+		[]notification{
+			{"before", attempt, nil},
+			{method: "handle action"},
+			{"after", attempt, nil},
+		},
+		listener.notifications,
+	)
+}
+
+func TestStateMachine_Listener_InvalidTransition(t *testing.T) {
+	listener := new(recordingListener)
+
+	sm := fsm.NewStateMachine(nil, nil, fsm.WithListener(listener))
+
+	err := sm.Trigger("current_state", "event")
+	require.Error(t, err)
+
+	attempt := fsm.Attempt{
+		CurrentState: "current_state",
+		Event:        "event",
+	}
+
+	assert.Equal(
+		t,
+		[]notification{
+			{"before", attempt, nil},
+			{"after", attempt, err},
+		},
+		listener.notifications,
+	)
+}
+
+func TestStateMachine_Listener_DelegateError(t *testing.T) {
+	listener := new(recordingListener)
+	delegate := new(mocks.Delegate)
+	transitions := []fsm.Transition{
+		{
+			FromState: "current_state",
+			Event:     "event",
+			ToState:   "next_state",
+			Action:    "action",
+		},
+	}
+
+	delegate.On("Handle", "action", "current_state", "next_state", mock.Anything).Return(errors.New("error"))
+
+	sm := fsm.NewStateMachine(delegate, transitions, fsm.WithListener(listener))
+
+	err := sm.Trigger("current_state", "event")
+	require.Error(t, err)
+
+	require.Len(t, listener.notifications, 2)
+	assert.Equal(t, err, listener.notifications[1].err)
+	assert.IsType(t, &fsm.DelegateError{}, listener.notifications[1].err)
+}
+
+func TestStateMachine_Listener_Subject(t *testing.T) {
+	listener := new(recordingListener)
+	subject := new(mocks.MutableSubject)
+	transitions := []fsm.Transition{
+		{
+			FromState: "current_state",
+			Event:     "event",
+			ToState:   "next_state",
+		},
+	}
+
+	subject.On("GetState").Return("current_state")
+	subject.On("SetState", "next_state").Return()
+
+	sm := fsm.NewStateMachine(nil, transitions, fsm.WithListener(listener))
+
+	err := sm.TriggerSubject(subject, "event")
+	require.NoError(t, err)
+
+	attempt := fsm.Attempt{
+		Subject:      subject,
+		CurrentState: "current_state",
+		Event:        "event",
+		Args:         []interface{}{subject},
+		Transition:   &transitions[0],
+	}
+
+	assert.Equal(
+		t,
+		[]notification{
+			{"before", attempt, nil},
+			{"after", attempt, nil},
+		},
+		listener.notifications,
+	)
+}
+
+func TestStateMachine_Listener_Regions(t *testing.T) {
+	listener := new(recordingListener)
+
+	sm := fsm.NewStateMachine(nil, fulfilmentTransitions(), fsm.WithStates(fulfilmentStates()), fsm.WithListener(listener))
+
+	_, err := sm.TriggerStates([]string{"paid", "unshipped"}, "reset")
+	require.NoError(t, err)
+
+	// Only regions with a matching transition are notified
+	require.Len(t, listener.notifications, 2)
+	assert.Equal(t, "unshipped", listener.notifications[0].attempt.CurrentState)
+	assert.Equal(t, "unshipped", listener.notifications[0].attempt.Transition.ToState)
+}
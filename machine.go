@@ -0,0 +1,199 @@
+package fsm
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// TypedTransition represents a state transition with typed states and events.
+type TypedTransition[S ~string, E ~string] struct {
+	FromState S
+	Event     E
+	ToState   S
+	Action    string
+
+	// Actions are executed in order after Action.
+	Actions []string
+
+	// FromStates are additional source states, see Transition for details.
+	FromStates []S
+
+	// ExceptStates are the states a wildcard transition (from AnyState) cannot be triggered in.
+	ExceptStates []S
+
+	// Guard is the name of a guard which has to pass for the transition to be selected.
+	Guard string
+}
+
+// TypedSubject represents a stateful structure exposing it's current typed state.
+type TypedSubject[S ~string] interface {
+	GetState() S
+}
+
+// TypedMutableSubject is a TypedSubject whose state is committed by the state machine.
+type TypedMutableSubject[S ~string] interface {
+	TypedSubject[S]
+
+	// SetState sets the state of the subject.
+	SetState(state S)
+}
+
+// TypedDelegate is responsible for handling actions of a Machine.
+//
+// See Delegate for details.
+type TypedDelegate[S ~string, T any, P any] interface {
+	// Handle handles transition actions.
+	Handle(ctx context.Context, action string, fromState S, toState S, subject T, payload P) error
+}
+
+// TypedDelegateFunc is a function implementing the TypedDelegate interface.
+type TypedDelegateFunc[S ~string, T any, P any] func(ctx context.Context, action string, fromState S, toState S, subject T, payload P) error
+
+// Handle calls the underlying function.
+func (fn TypedDelegateFunc[S, T, P]) Handle(ctx context.Context, action string, fromState S, toState S, subject T, payload P) error {
+	return fn(ctx, action, fromState, toState, subject, payload)
+}
+
+// TypedActionMuxDelegate allows to register a typed delegate per action.
+type TypedActionMuxDelegate[S ~string, T any, P any] struct {
+	delegates map[string]TypedDelegate[S, T, P]
+}
+
+// NewTypedActionMuxDelegate returns a new TypedActionMuxDelegate.
+func NewTypedActionMuxDelegate[S ~string, T any, P any](delegates map[string]TypedDelegate[S, T, P]) *TypedActionMuxDelegate[S, T, P] {
+	return &TypedActionMuxDelegate[S, T, P]{delegates}
+}
+
+// Handle calls the underlying delegate for an action if any.
+func (d *TypedActionMuxDelegate[S, T, P]) Handle(ctx context.Context, action string, fromState S, toState S, subject T, payload P) error {
+	if delegate, ok := d.delegates[action]; ok {
+		return delegate.Handle(ctx, action, fromState, toState, subject, payload)
+	}
+
+	return nil
+}
+
+// Machine is a type-safe state machine with typed states, events, subject and payload.
+//
+// It is built on top of StateMachine: states and events are string based,
+// so the same options (guards, states, etc) apply to both of them.
+type Machine[S ~string, E ~string, T TypedSubject[S], P any] struct {
+	stateMachine *StateMachine
+}
+
+// NewMachine returns a new Machine.
+func NewMachine[S ~string, E ~string, T TypedSubject[S], P any](
+	delegate TypedDelegate[S, T, P],
+	transitions []TypedTransition[S, E],
+	opts ...Option,
+) *Machine[S, E, T, P] {
+	ts := make([]Transition, len(transitions))
+	for i, t := range transitions {
+		ts[i] = Transition{
+			FromState:    string(t.FromState),
+			Event:        string(t.Event),
+			ToState:      string(t.ToState),
+			Action:       t.Action,
+			Actions:      t.Actions,
+			FromStates:   stringStates(t.FromStates),
+			ExceptStates: stringStates(t.ExceptStates),
+			Guard:        t.Guard,
+		}
+	}
+
+	return &Machine[S, E, T, P]{
+		stateMachine: NewStateMachine(&typedDelegateAdapter[S, T, P]{delegate}, ts, opts...),
+	}
+}
+
+// Trigger fires an event using the subject's current state and calls the underlying delegate.
+//
+// When the subject is a TypedMutableSubject, the new state is committed after the actions succeeded.
+// When the subject is a HistorySubject, its history is committed as well.
+// Transitions entering multiple states (eg. the regions of a parallel state) fail with a CompoundStateError.
+func (m *Machine[S, E, T, P]) Trigger(ctx context.Context, subject T, event E, payload P) error {
+	currentState := string(subject.GetState())
+
+	history := m.stateMachine.subjectHistory(subject)
+
+	states, err := m.stateMachine.trigger(ctx, subject, []string{currentState}, string(event), []interface{}{subject, payload}, history, true)
+	if err != nil {
+		return err
+	}
+
+	m.stateMachine.commitHistory(subject, history)
+
+	if mSubject, ok := interface{}(subject).(TypedMutableSubject[S]); ok && states[0] != currentState {
+		mSubject.SetState(S(states[0]))
+	}
+
+	return nil
+}
+
+// StateMachine returns the underlying untyped state machine.
+func (m *Machine[S, E, T, P]) StateMachine() *StateMachine {
+	return m.stateMachine
+}
+
+// typedDelegateAdapter adapts a TypedDelegate to the Delegate interface.
+//
+// It expects the subject and the payload as arguments.
+type typedDelegateAdapter[S ~string, T any, P any] struct {
+	delegate TypedDelegate[S, T, P]
+}
+
+// Handle calls the underlying delegate with an empty context.
+func (d *typedDelegateAdapter[S, T, P]) Handle(action string, fromState string, toState string, args []interface{}) error {
+	return d.HandleContext(context.Background(), action, fromState, toState, args)
+}
+
+// HandleContext calls the underlying delegate with the subject and the payload.
+//
+// It returns an error if the arguments are not of the expected types.
+func (d *typedDelegateAdapter[S, T, P]) HandleContext(ctx context.Context, action string, fromState string, toState string, args []interface{}) error {
+	subject, err := typedArgument[T](args, 0, "subject")
+	if err != nil {
+		return err
+	}
+
+	payload, err := typedArgument[P](args, 1, "payload")
+	if err != nil {
+		return err
+	}
+
+	return d.delegate.Handle(ctx, action, S(fromState), S(toState), subject, payload)
+}
+
+// typedArgument returns an argument as the expected type.
+//
+// Missing (and nil) arguments are returned as zero values.
+func typedArgument[V any](args []interface{}, i int, name string) (V, error) {
+	var value V
+
+	// Arguments are always set by Machine, but the untyped state machine might be triggered directly
+	if len(args) <= i || args[i] == nil {
+		return value, nil
+	}
+
+	value, ok := args[i].(V)
+	if !ok {
+		return value, fmt.Errorf("%s argument has type %T instead of %s", name, args[i], reflect.TypeOf(&value).Elem())
+	}
+
+	return value, nil
+}
+
+// stringStates converts typed states to strings.
+func stringStates[S ~string](states []S) []string {
+	if states == nil {
+		return nil
+	}
+
+	names := make([]string, len(states))
+	for i, state := range states {
+		names[i] = string(state)
+	}
+
+	return names
+}
@@ -0,0 +1,136 @@
+package fsm_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/goph/fsm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type orderState string
+
+type orderEvent string
+
+type order struct {
+	state orderState
+}
+
+func (o *order) GetState() orderState {
+	return o.state
+}
+
+type mutableOrder struct {
+	order
+}
+
+func (o *mutableOrder) SetState(state orderState) {
+	o.state = state
+}
+
+type payment struct {
+	amount int
+}
+
+func orderTransitions() []fsm.TypedTransition[orderState, orderEvent] {
+	return []fsm.TypedTransition[orderState, orderEvent]{
+		{
+			FromState: "new",
+			Event:     "pay",
+			ToState:   "paid",
+			Action:    "pay",
+		},
+	}
+}
+
+func TestMachine(t *testing.T) {
+	var called bool
+
+	delegate := fsm.TypedDelegateFunc[orderState, *order, payment](
+		func(ctx context.Context, action string, fromState orderState, toState orderState, subject *order, payload payment) error {
+			called = true
+
+			assert.Equal(t, "pay", action)
+			assert.Equal(t, orderState("new"), fromState)
+			assert.Equal(t, orderState("paid"), toState)
+			assert.Equal(t, 100, payload.amount)
+
+			subject.state = toState
+
+			return nil
+		},
+	)
+
+	m := fsm.NewMachine[orderState, orderEvent, *order, payment](delegate, orderTransitions())
+
+	o := &order{"new"}
+
+	err := m.Trigger(context.Background(), o, "pay", payment{100})
+
+	require.NoError(t, err)
+	assert.True(t, called)
+	assert.Equal(t, orderState("paid"), o.state)
+}
+
+func TestMachine_InvalidTransition(t *testing.T) {
+	delegate := fsm.NewTypedActionMuxDelegate[orderState, *order, payment](nil)
+
+	m := fsm.NewMachine[orderState, orderEvent, *order, payment](delegate, orderTransitions())
+
+	err := m.Trigger(context.Background(), &order{"paid"}, "pay", payment{100})
+
+	require.Error(t, err)
+	assert.IsType(t, &fsm.InvalidTransitionError{}, err)
+}
+
+func TestMachine_UnexpectedArgumentType(t *testing.T) {
+	delegate := fsm.NewTypedActionMuxDelegate[orderState, *order, payment](nil)
+
+	m := fsm.NewMachine[orderState, orderEvent, *order, payment](delegate, orderTransitions())
+
+	// The untyped state machine can be triggered with any arguments
+	err := m.StateMachine().Trigger("new", "pay", &order{"new"}, 100)
+
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, fsm.ErrDelegateFailed))
+	assert.EqualError(
+		t,
+		errors.Unwrap(err),
+		"payload argument has type int instead of fsm_test.payment",
+	)
+}
+
+func TestTypedActionMuxDelegate(t *testing.T) {
+	delegateErr := errors.New("error happened")
+
+	delegate := fsm.NewTypedActionMuxDelegate(map[string]fsm.TypedDelegate[orderState, *order, payment]{
+		"pay": fsm.TypedDelegateFunc[orderState, *order, payment](
+			func(ctx context.Context, action string, fromState orderState, toState orderState, subject *order, payload payment) error {
+				return delegateErr
+			},
+		),
+	})
+
+	err := delegate.Handle(context.Background(), "pay", "new", "paid", &order{"new"}, payment{100})
+
+	assert.Equal(t, delegateErr, err)
+
+	err = delegate.Handle(context.Background(), "refund", "paid", "refunded", &order{"paid"}, payment{100})
+
+	assert.NoError(t, err)
+}
+
+func TestMachine_TypedMutableSubject(t *testing.T) {
+	delegate := fsm.NewTypedActionMuxDelegate[orderState, *mutableOrder, payment](nil)
+
+	m := fsm.NewMachine[orderState, orderEvent, *mutableOrder, payment](delegate, orderTransitions())
+
+	o := &mutableOrder{order{"new"}}
+
+	err := m.Trigger(context.Background(), o, "pay", payment{100})
+
+	require.NoError(t, err)
+	assert.Equal(t, orderState("paid"), o.state)
+}
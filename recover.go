@@ -0,0 +1,71 @@
+package fsm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+)
+
+// ErrDelegatePanicked matches DelegatePanicError.
+var ErrDelegatePanicked = errors.New("delegate panicked")
+
+// DelegatePanicError is returned (wrapped in a DelegateError) when a delegate panics and panic recovery is enabled.
+type DelegatePanicError struct {
+	value interface{}
+	stack []byte
+}
+
+// Value returns the value the delegate panicked with.
+func (e *DelegatePanicError) Value() interface{} {
+	return e.value
+}
+
+// Stack returns the stack trace of the goroutine at the time of the panic.
+func (e *DelegatePanicError) Stack() []byte {
+	return e.stack
+}
+
+// Is matches ErrDelegatePanicked.
+func (e *DelegatePanicError) Is(target error) bool {
+	return target == ErrDelegatePanicked
+}
+
+// Unwrap returns the value the delegate panicked with if it's an error.
+func (e *DelegatePanicError) Unwrap() error {
+	if err, ok := e.value.(error); ok {
+		return err
+	}
+
+	return nil
+}
+
+// Error returns the formatted error message.
+func (e *DelegatePanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.value)
+}
+
+// WithPanicRecovery recovers from panics in the delegate.
+//
+// A panic is converted into a DelegatePanicError, which is returned wrapped in a DelegateError
+// just like an error returned by the delegate: the transition fails and the state doesn't change.
+func WithPanicRecovery() Option {
+	return func(sm *StateMachine) {
+		sm.recoverPanics = true
+	}
+}
+
+// handle calls the delegate and recovers from its panics if panic recovery is enabled.
+func (sm *StateMachine) handle(ctx context.Context, action string, fromState string, toState string, args []interface{}) (err error) {
+	if !sm.recoverPanics {
+		return handleContext(ctx, sm.delegate, action, fromState, toState, args)
+	}
+
+	defer func() {
+		if value := recover(); value != nil {
+			err = &DelegatePanicError{value, debug.Stack()}
+		}
+	}()
+
+	return handleContext(ctx, sm.delegate, action, fromState, toState, args)
+}
@@ -0,0 +1,138 @@
+package fsm_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/goph/fsm"
+	"github.com/goph/fsm/internal/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStateMachine_WithPanicRecovery(t *testing.T) {
+	delegate := new(mocks.Delegate)
+	transitions := []fsm.Transition{
+		{
+			FromState: "current_state",
+			Event:     "event",
+			ToState:   "next_state",
+			Action:    "action",
+		},
+	}
+
+	subject := new(mocks.MutableSubject)
+
+	subject.On("GetState").Return("current_state")
+
+	delegate.
+		On("Handle", "action", "current_state", "next_state", []interface{}{subject}).
+		Run(func(args mock.Arguments) {
+			panic("boom")
+		})
+
+	sm := fsm.NewStateMachine(delegate, transitions, fsm.WithPanicRecovery())
+
+	err := sm.TriggerSubject(subject, "event")
+
+	require.Error(t, err)
+
+	var derr *fsm.DelegateError
+	require.True(t, errors.As(err, &derr))
+
+	assert.Equal(t, "action", derr.Action())
+	assert.Equal(t, "next_state", derr.NextState())
+	assert.True(t, errors.Is(err, fsm.ErrDelegateFailed))
+	assert.True(t, errors.Is(err, fsm.ErrDelegatePanicked))
+	assert.EqualError(t, err, "delegate reported an error during transition from \"current_state\" state triggered by \"event\" event: panic: boom")
+
+	var perr *fsm.DelegatePanicError
+	require.True(t, errors.As(err, &perr))
+
+	assert.Equal(t, "boom", perr.Value())
+	assert.Contains(t, string(perr.Stack()), "panic")
+
+	subject.AssertNotCalled(t, "SetState", "next_state")
+}
+
+func TestStateMachine_WithPanicRecovery_Error(t *testing.T) {
+	delegate := new(mocks.Delegate)
+	transitions := []fsm.Transition{
+		{
+			FromState: "current_state",
+			Event:     "event",
+			ToState:   "next_state",
+			Action:    "action",
+		},
+	}
+
+	panicErr := errors.New("error happened")
+
+	delegate.
+		On("Handle", "action", "current_state", "next_state", []interface{}(nil)).
+		Run(func(args mock.Arguments) {
+			panic(panicErr)
+		})
+
+	sm := fsm.NewStateMachine(delegate, transitions, fsm.WithPanicRecovery())
+
+	err := sm.Trigger("current_state", "event")
+
+	assert.True(t, errors.Is(err, panicErr))
+}
+
+func TestStateMachine_WithPanicRecovery_StopPropagation(t *testing.T) {
+	delegate := new(mocks.Delegate)
+	transitions := []fsm.Transition{
+		{
+			FromState: "current_state",
+			Event:     "event",
+			ToState:   "next_state",
+			Action:    "action",
+		},
+	}
+
+	subject := new(mocks.MutableSubject)
+
+	subject.On("GetState").Return("current_state")
+
+	delegate.
+		On("Handle", "action", "current_state", "next_state", []interface{}{subject}).
+		Run(func(args mock.Arguments) {
+			panic(fsm.StopPropagation)
+		})
+
+	sm := fsm.NewStateMachine(delegate, transitions, fsm.WithPanicRecovery())
+
+	err := sm.TriggerSubject(subject, "event")
+
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, fsm.ErrDelegatePanicked))
+
+	subject.AssertNotCalled(t, "SetState", "next_state")
+}
+
+func TestStateMachine_WithoutPanicRecovery(t *testing.T) {
+	delegate := new(mocks.Delegate)
+	transitions := []fsm.Transition{
+		{
+			FromState: "current_state",
+			Event:     "event",
+			ToState:   "next_state",
+			Action:    "action",
+		},
+	}
+
+	delegate.
+		On("Handle", "action", "current_state", "next_state", []interface{}(nil)).
+		Run(func(args mock.Arguments) {
+			panic("boom")
+		})
+
+	sm := fsm.NewStateMachine(delegate, transitions)
+
+	assert.PanicsWithValue(t, "boom", func() {
+		sm.Trigger("current_state", "event")
+	})
+}
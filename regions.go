@@ -0,0 +1,174 @@
+package fsm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// CompoundSubject represents a stateful structure with orthogonal regions exposing it's active states.
+type CompoundSubject interface {
+	// GetStates returns the active leaf state of every region.
+	GetStates() []string
+}
+
+// RegionsError is returned when transitions fail in more than one region.
+type RegionsError struct {
+	errs []error
+}
+
+// Errors returns the errors of the individual regions.
+func (e *RegionsError) Errors() []error {
+	return e.errs
+}
+
+// Unwrap returns the errors of the individual regions, so that errors.Is and errors.As can inspect them.
+func (e *RegionsError) Unwrap() []error {
+	return e.errs
+}
+
+// Error returns the formatted error message.
+func (e *RegionsError) Error() string {
+	messages := make([]string, len(e.errs))
+	for i, err := range e.errs {
+		messages[i] = err.Error()
+	}
+
+	return fmt.Sprintf("%d regions reported errors: %s", len(e.errs), strings.Join(messages, "; "))
+}
+
+// CompoundStateError is returned when a transition of a Subject would enter multiple states
+// (eg. the regions of a parallel state), since the subject cannot hold them.
+//
+// Such transitions can be triggered using TriggerCompoundSubject.
+type CompoundStateError struct {
+	*transitionError
+
+	nextStates []string
+}
+
+// Code returns CodeCompoundState.
+func (e *CompoundStateError) Code() ErrorCode {
+	return CodeCompoundState
+}
+
+// Is matches ErrCompoundState.
+func (e *CompoundStateError) Is(target error) bool {
+	return target == ErrCompoundState
+}
+
+// Error returns the formatted error message.
+func (e *CompoundStateError) Error() string {
+	return fmt.Sprintf(
+		"transition from %q state triggered by %q event enters multiple states (%s), use TriggerCompoundSubject instead",
+		e.currentState,
+		e.event,
+		strings.Join(e.nextStates, ", "),
+	)
+}
+
+// NextStates returns the states the transition would enter.
+func (e *CompoundStateError) NextStates() []string {
+	return e.nextStates
+}
+
+// equalStates checks whether two lists of states are the same.
+func equalStates(states []string, other []string) bool {
+	if len(states) != len(other) {
+		return false
+	}
+
+	for i := range states {
+		if states[i] != other[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// combineErrors returns nil, the only error or a RegionsError depending on the number of errors.
+func combineErrors(errs []error) error {
+	switch len(errs) {
+	case 0:
+		return nil
+
+	case 1:
+		return errs[0]
+	}
+
+	return &RegionsError{errs}
+}
+
+// MutableCompoundSubject is a CompoundSubject whose states are committed by the state machine.
+type MutableCompoundSubject interface {
+	CompoundSubject
+
+	// SetStates sets the active states of the subject.
+	SetStates(states []string)
+}
+
+// TriggerStates fires an event in every region having a matching transition and calls the underlying delegate.
+//
+// It returns the active states after the transitions.
+// Regions without a matching transition keep their state,
+// unless none of the regions could handle the event: in that case their errors are returned.
+//
+// The states of regions whose transition failed are left untouched.
+func (sm *StateMachine) TriggerStates(currentStates []string, event string, args ...interface{}) ([]string, error) {
+	return sm.TriggerStatesContext(context.Background(), currentStates, event, args...)
+}
+
+// TriggerStatesContext fires an event with a context in every region having a matching transition.
+//
+// See TriggerStates for details.
+func (sm *StateMachine) TriggerStatesContext(
+	ctx context.Context,
+	currentStates []string,
+	event string,
+	args ...interface{},
+) ([]string, error) {
+	states, err := sm.trigger(ctx, nil, currentStates, event, args, nil, false)
+
+	return append([]string(nil), states...), err
+}
+
+// TriggerCompoundSubject triggers an event using the Subject's active states.
+//
+// It also passes the subject as the first argument.
+// When the subject is a MutableCompoundSubject, the new states are committed after the transitions.
+// The states of regions whose transition failed are left untouched.
+// When the subject is a HistorySubject, its history is committed as well.
+func (sm *StateMachine) TriggerCompoundSubject(subject CompoundSubject, event string, args ...interface{}) ([]string, error) {
+	return sm.TriggerCompoundSubjectContext(context.Background(), subject, event, args...)
+}
+
+// TriggerCompoundSubjectContext triggers an event with a context using the Subject's active states.
+//
+// See TriggerCompoundSubject for details.
+func (sm *StateMachine) TriggerCompoundSubjectContext(
+	ctx context.Context,
+	subject CompoundSubject,
+	event string,
+	args ...interface{},
+) ([]string, error) {
+	args = prependSubject(subject, args)
+
+	history := sm.subjectHistory(subject)
+
+	currentStates := subject.GetStates()
+
+	states, err := sm.trigger(ctx, subject, currentStates, event, args, history, false)
+	states = append([]string(nil), states...)
+
+	// Some of the regions might have succeeded
+	if err == nil || !equalStates(states, currentStates) {
+		sm.commitHistory(subject, history)
+	}
+
+	if mSubject, ok := subject.(MutableCompoundSubject); ok && !equalStates(states, currentStates) {
+		mSubject.SetStates(states)
+	}
+
+	return states, err
+}
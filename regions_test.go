@@ -0,0 +1,306 @@
+package fsm_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/goph/fsm"
+	"github.com/goph/fsm/internal/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// fulfilmentStates returns a parallel state with a payment and a shipping region.
+func fulfilmentStates() []fsm.State {
+	return []fsm.State{
+		{
+			Name:     "processing",
+			Parallel: true,
+			OnEnter:  "enter_processing",
+			OnExit:   "exit_processing",
+		},
+		{
+			Name:    "payment",
+			Parent:  "processing",
+			Initial: "unpaid",
+		},
+		{
+			Name:   "unpaid",
+			Parent: "payment",
+		},
+		{
+			Name:   "paid",
+			Parent: "payment",
+		},
+		{
+			Name:    "shipping",
+			Parent:  "processing",
+			Initial: "unshipped",
+		},
+		{
+			Name:   "unshipped",
+			Parent: "shipping",
+		},
+		{
+			Name:   "shipped",
+			Parent: "shipping",
+		},
+	}
+}
+
+func fulfilmentTransitions() []fsm.Transition {
+	return []fsm.Transition{
+		{
+			FromState: "new",
+			Event:     "place",
+			ToState:   "processing",
+			Action:    "place",
+		},
+		{
+			FromState: "unpaid",
+			Event:     "pay",
+			ToState:   "paid",
+			Action:    "pay",
+		},
+		{
+			FromState: "unshipped",
+			Event:     "ship",
+			ToState:   "shipped",
+			Action:    "ship",
+		},
+		{
+			FromState: "unpaid",
+			Event:     "reset",
+			ToState:   "unpaid",
+		},
+		{
+			FromState: "unshipped",
+			Event:     "reset",
+			ToState:   "unshipped",
+		},
+		{
+			FromState: "processing",
+			Event:     "cancel",
+			ToState:   "cancelled",
+			Action:    "cancel",
+		},
+	}
+}
+
+func TestStateMachine_TriggerStates_EnterParallelState(t *testing.T) {
+	delegate := new(mocks.Delegate)
+
+	calls := new(recorder)
+
+	delegate.On("Handle", mock.Anything, "new", "processing", []interface{}{"argument"}).Return(calls.record)
+
+	sm := fsm.NewStateMachine(delegate, fulfilmentTransitions(), fsm.WithStates(fulfilmentStates()))
+
+	states, err := sm.TriggerStates([]string{"new"}, "place", "argument")
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"unpaid", "unshipped"}, states)
+	assert.Equal(t, []string{"place", "enter_processing"}, calls.actions)
+}
+
+func TestStateMachine_TriggerSubject_EnterParallelState(t *testing.T) {
+	delegate := new(mocks.Delegate)
+	subject := new(mocks.MutableSubject)
+
+	subject.On("GetState").Return("new")
+
+	sm := fsm.NewStateMachine(delegate, fulfilmentTransitions(), fsm.WithStates(fulfilmentStates()))
+
+	err := sm.TriggerSubject(subject, "place", "argument")
+
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, fsm.ErrCompoundState))
+	assert.EqualError(
+		t,
+		err,
+		"transition from \"new\" state triggered by \"place\" event enters multiple states (unpaid, unshipped), use TriggerCompoundSubject instead",
+	)
+
+	var cerr *fsm.CompoundStateError
+	require.True(t, errors.As(err, &cerr))
+
+	assert.Equal(t, []string{"unpaid", "unshipped"}, cerr.NextStates())
+
+	// Nothing happens before the transition is rejected
+	delegate.AssertNotCalled(t, "Handle", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	subject.AssertNotCalled(t, "SetState", mock.Anything)
+}
+
+func TestStateMachine_TriggerSubject_EnterParallelState_VersionedSubject(t *testing.T) {
+	delegate := new(mocks.Delegate)
+	subject := new(mocks.VersionedSubject)
+
+	subject.On("GetState").Return("new")
+	subject.On("GetVersion").Return(int64(1))
+
+	sm := fsm.NewStateMachine(delegate, fulfilmentTransitions(), fsm.WithStates(fulfilmentStates()))
+
+	err := sm.TriggerSubject(subject, "place", "argument")
+
+	assert.IsType(t, &fsm.CompoundStateError{}, err)
+
+	delegate.AssertNotCalled(t, "Handle", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	subject.AssertNotCalled(t, "CompareAndSwapState", mock.Anything, mock.Anything)
+}
+
+func TestStateMachine_TriggerStates_SingleRegion(t *testing.T) {
+	delegate := new(mocks.Delegate)
+	delegate.On("Handle", "pay", "unpaid", "paid", []interface{}{"argument"}).Return(nil)
+
+	sm := fsm.NewStateMachine(delegate, fulfilmentTransitions(), fsm.WithStates(fulfilmentStates()))
+
+	states, err := sm.TriggerStates([]string{"unpaid", "unshipped"}, "pay", "argument")
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"paid", "unshipped"}, states)
+
+	delegate.AssertExpectations(t)
+}
+
+func TestStateMachine_TriggerStates_EveryRegion(t *testing.T) {
+	delegate := new(mocks.Delegate)
+
+	sm := fsm.NewStateMachine(delegate, fulfilmentTransitions(), fsm.WithStates(fulfilmentStates()))
+
+	states, err := sm.TriggerStates([]string{"unpaid", "unshipped"}, "reset", "argument")
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"unpaid", "unshipped"}, states)
+}
+
+func TestStateMachine_TriggerStates_ParentTransition(t *testing.T) {
+	delegate := new(mocks.Delegate)
+
+	calls := new(recorder)
+
+	delegate.On("Handle", mock.Anything, "paid", "cancelled", []interface{}{"argument"}).Return(calls.record)
+
+	sm := fsm.NewStateMachine(delegate, fulfilmentTransitions(), fsm.WithStates(fulfilmentStates()))
+
+	states, err := sm.TriggerStates([]string{"paid", "unshipped"}, "cancel", "argument")
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cancelled"}, states)
+	assert.Equal(t, []string{"exit_processing", "cancel"}, calls.actions)
+}
+
+func TestStateMachine_TriggerStates_ExitOrder(t *testing.T) {
+	delegate := new(mocks.Delegate)
+	transitions := []fsm.Transition{
+		{
+			FromState: "p",
+			Event:     "done",
+			ToState:   "finished",
+			Action:    "act",
+		},
+	}
+
+	calls := new(recorder)
+
+	delegate.On("Handle", mock.Anything, "a", "finished", []interface{}(nil)).Return(calls.record)
+
+	states := []fsm.State{
+		{Name: "p", Parallel: true, OnExit: "exit_p"},
+		{Name: "r1", Parent: "p", Initial: "a", OnExit: "exit_r1"},
+		{Name: "a", Parent: "r1", OnExit: "exit_a"},
+		{Name: "r2", Parent: "p", Initial: "b", OnExit: "exit_r2"},
+		{Name: "b", Parent: "r2", OnExit: "exit_b"},
+	}
+
+	sm := fsm.NewStateMachine(delegate, transitions, fsm.WithStates(states))
+
+	nextStates, err := sm.TriggerStates([]string{"a", "b"}, "done")
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"finished"}, nextStates)
+
+	// Parents are left after all of their active descendants
+	assert.Equal(t, []string{"exit_a", "exit_r1", "exit_b", "exit_r2", "exit_p", "act"}, calls.actions)
+}
+
+func TestStateMachine_TriggerStates_InvalidTransition(t *testing.T) {
+	delegate := new(mocks.Delegate)
+
+	sm := fsm.NewStateMachine(delegate, fulfilmentTransitions(), fsm.WithStates(fulfilmentStates()))
+
+	states, err := sm.TriggerStates([]string{"unpaid", "unshipped"}, "refund", "argument")
+
+	require.Error(t, err)
+	assert.Equal(t, []string{"unpaid", "unshipped"}, states)
+
+	rerr := err.(*fsm.RegionsError)
+
+	require.Len(t, rerr.Errors(), 2)
+	assert.IsType(t, &fsm.InvalidTransitionError{}, rerr.Errors()[0])
+	assert.IsType(t, &fsm.InvalidTransitionError{}, rerr.Errors()[1])
+	assert.True(t, errors.Is(err, fsm.ErrInvalidTransition))
+	assert.EqualError(
+		t,
+		rerr,
+		"2 regions reported errors: cannot transition from \"unpaid\" state triggered by \"refund\" event; cannot transition from \"unshipped\" state triggered by \"refund\" event",
+	)
+}
+
+func TestStateMachine_TriggerStates_DelegateError(t *testing.T) {
+	delegate := new(mocks.Delegate)
+
+	delegateErr := errors.New("error happened")
+
+	delegate.On("Handle", "pay", "unpaid", "paid", []interface{}{"argument"}).Return(delegateErr)
+
+	sm := fsm.NewStateMachine(delegate, fulfilmentTransitions(), fsm.WithStates(fulfilmentStates()))
+
+	states, err := sm.TriggerStates([]string{"unpaid", "unshipped"}, "pay", "argument")
+
+	require.Error(t, err)
+	assert.Equal(t, []string{"unpaid", "unshipped"}, states)
+
+	derr := err.(*fsm.DelegateError)
+
+	assert.Equal(t, delegateErr, derr.Cause())
+	assert.Equal(t, "unpaid", derr.CurrentState())
+}
+
+func TestStateMachine_TriggerCompoundSubject(t *testing.T) {
+	delegate := new(mocks.Delegate)
+	subject := new(mocks.CompoundSubject)
+
+	subject.On("GetStates").Return([]string{"unpaid", "unshipped"})
+
+	delegate.On("Handle", "ship", "unshipped", "shipped", []interface{}{subject, "argument"}).Return(nil)
+
+	sm := fsm.NewStateMachine(delegate, fulfilmentTransitions(), fsm.WithStates(fulfilmentStates()))
+
+	states, err := sm.TriggerCompoundSubject(subject, "ship", "argument")
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"unpaid", "shipped"}, states)
+
+	delegate.AssertExpectations(t)
+	subject.AssertExpectations(t)
+}
+
+func TestStateMachine_MutableCompoundSubject(t *testing.T) {
+	delegate := new(mocks.Delegate)
+	subject := new(mocks.MutableCompoundSubject)
+
+	subject.On("GetStates").Return([]string{"unpaid", "unshipped"})
+	subject.On("SetStates", []string{"unpaid", "shipped"})
+
+	delegate.On("Handle", "ship", "unshipped", "shipped", []interface{}{subject}).Return(nil)
+
+	sm := fsm.NewStateMachine(delegate, fulfilmentTransitions(), fsm.WithStates(fulfilmentStates()))
+
+	_, err := sm.TriggerCompoundSubject(subject, "ship")
+
+	require.NoError(t, err)
+
+	delegate.AssertExpectations(t)
+	subject.AssertExpectations(t)
+}
@@ -0,0 +1,412 @@
+package scxml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/goph/fsm"
+)
+
+// element is a node of the parsed document.
+type element struct {
+	name     xml.Name
+	attrs    []xml.Attr
+	children []*element
+	line     int
+}
+
+// attr returns the value of an attribute without a namespace.
+func (e *element) attr(name string) string {
+	for _, attr := range e.attrs {
+		if attr.Name.Space == "" && attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+
+	return ""
+}
+
+// parse parses a document into a tree of elements, recording the line of each element.
+func parse(data []byte) (*element, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+
+	var root *element
+	var stack []*element
+
+	for {
+		offset := decoder.InputOffset()
+
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		switch token := token.(type) {
+		case xml.StartElement:
+			e := &element{
+				name:  token.Name,
+				attrs: token.Attr,
+				line:  1 + bytes.Count(data[:offset], []byte("\n")),
+			}
+
+			if len(stack) == 0 {
+				root = e
+			} else {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, e)
+			}
+
+			stack = append(stack, e)
+
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		}
+	}
+
+	if root == nil {
+		return nil, fmt.Errorf("document has no root element")
+	}
+
+	return root, nil
+}
+
+// Read reads an SCXML document and converts it to a state machine definition.
+func Read(r io.Reader) (fsm.Definition, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return fsm.Definition{}, err
+	}
+
+	root, err := parse(data)
+	if err != nil {
+		return fsm.Definition{}, err
+	}
+
+	if root.name.Space != Namespace || root.name.Local != "scxml" {
+		return fsm.Definition{}, fmt.Errorf("line %d: root element is not an scxml element", root.line)
+	}
+
+	rd := new(reader)
+
+	rd.def.InitialState = rd.target(root, root.attr("initial"), "multiple initial states")
+	rd.states(root, "")
+
+	if rd.def.InitialState == "" {
+		rd.def.InitialState = rd.first(root)
+	}
+
+	if rd.err != nil {
+		return fsm.Definition{}, rd.err
+	}
+
+	return rd.def, nil
+}
+
+// reader converts elements to a definition and records the first error.
+type reader struct {
+	def fsm.Definition
+	err error
+}
+
+// unsupported records an unsupported feature.
+func (r *reader) unsupported(e *element, feature string) {
+	if r.err == nil {
+		r.err = &UnsupportedError{Feature: feature, Line: e.line}
+	}
+}
+
+// errorf records an error of an invalid document.
+func (r *reader) errorf(e *element, format string, args ...interface{}) {
+	if r.err == nil {
+		r.err = fmt.Errorf("line %d: %s", e.line, fmt.Sprintf(format, args...))
+	}
+}
+
+// states converts the child states of an element.
+func (r *reader) states(e *element, parent string) {
+	for _, child := range e.children {
+		if child.name.Space != Namespace {
+			r.unsupported(child, fmt.Sprintf("<%s> elements in %q namespace", child.name.Local, child.name.Space))
+
+			continue
+		}
+
+		switch child.name.Local {
+		case "state", "parallel", "final":
+			r.state(child, parent)
+
+		case "history":
+			if parent == "" {
+				r.errorf(child, "history state %q has no parent", child.attr("id"))
+
+				continue
+			}
+
+			r.history(child, parent)
+
+		case "initial", "onentry", "onexit", "transition":
+			if parent == "" {
+				r.errorf(child, "unexpected <%s> element", child.name.Local)
+			}
+
+		case "datamodel":
+			r.unsupported(child, "data models")
+
+		case "script":
+			r.unsupported(child, "scripts")
+
+		case "invoke":
+			r.unsupported(child, "invoked services")
+
+		case "donedata":
+			r.unsupported(child, "done data")
+
+		default:
+			r.unsupported(child, fmt.Sprintf("<%s> elements", child.name.Local))
+		}
+	}
+}
+
+// state converts a state, parallel or final element.
+func (r *reader) state(e *element, parent string) {
+	state := fsm.State{
+		Name:     e.attr("id"),
+		Parent:   parent,
+		Parallel: e.name.Local == "parallel",
+		Final:    e.name.Local == "final",
+		Initial:  r.target(e, e.attr("initial"), "multiple initial states"),
+	}
+
+	if state.Name == "" {
+		r.unsupported(e, "states without an id")
+
+		return
+	}
+
+	i := len(r.def.States)
+	r.def.States = append(r.def.States, state)
+
+	for _, child := range e.children {
+		if child.name.Space != Namespace {
+			continue
+		}
+
+		switch child.name.Local {
+		case "onentry":
+			state.OnEnter = r.action(child, state.OnEnter)
+
+		case "onexit":
+			state.OnExit = r.action(child, state.OnExit)
+
+		case "transition":
+			r.transition(child, state.Name)
+
+		case "initial":
+			if state.Initial != "" {
+				r.errorf(child, "state %q has multiple initial states", state.Name)
+			}
+
+			state.Initial = r.defaultTarget(child)
+		}
+	}
+
+	r.states(e, state.Name)
+
+	// SCXML enters the first child of compound states without an initial state
+	if state.Initial == "" && !state.Parallel {
+		state.Initial = r.first(e)
+	}
+
+	r.def.States[i] = state
+}
+
+// history converts a history element.
+func (r *reader) history(e *element, parent string) {
+	state := fsm.State{
+		Name:    e.attr("id"),
+		Parent:  parent,
+		History: fsm.HistoryType(e.attr("type")),
+	}
+
+	if state.Name == "" {
+		r.unsupported(e, "history states without an id")
+
+		return
+	}
+
+	switch state.History {
+	case "":
+		state.History = fsm.ShallowHistory
+
+	case fsm.ShallowHistory, fsm.DeepHistory:
+
+	default:
+		r.errorf(e, "history state %q has unknown type %q", state.Name, state.History)
+	}
+
+	for _, child := range e.children {
+		if child.name.Space == Namespace && child.name.Local == "transition" {
+			state.Initial = r.defaultTarget(child)
+		}
+	}
+
+	r.def.States = append(r.def.States, state)
+}
+
+// defaultTarget returns the target of the default transition of initial and history elements.
+func (r *reader) defaultTarget(e *element) string {
+	if e.name.Local != "transition" {
+		for _, child := range e.children {
+			if child.name.Space == Namespace && child.name.Local == "transition" {
+				return r.defaultTarget(child)
+			}
+		}
+
+		r.errorf(e, "<%s> element has no transition", e.name.Local)
+
+		return ""
+	}
+
+	if len(e.children) > 0 {
+		r.unsupported(e.children[0], "executable content in default transitions")
+	}
+
+	return r.target(e, e.attr("target"), "multiple targets")
+}
+
+// transition converts a transition element.
+//
+// Transitions triggered by multiple events are converted to a transition per event.
+func (r *reader) transition(e *element, from string) {
+	events := strings.Fields(e.attr("event"))
+	if len(events) == 0 {
+		r.unsupported(e, "eventless transitions")
+
+		return
+	}
+
+	to := r.target(e, e.attr("target"), "multiple targets")
+	if to == "" {
+		r.unsupported(e, "targetless transitions")
+
+		return
+	}
+
+	if e.attr("type") == "internal" {
+		r.unsupported(e, "internal transitions")
+	}
+
+	var metadata map[string]string
+
+	for _, child := range e.children {
+		if child.name.Space == ActionNamespace && child.name.Local == "meta" {
+			if metadata == nil {
+				metadata = make(map[string]string)
+			}
+
+			metadata[child.attr("name")] = child.attr("value")
+		}
+	}
+
+	var actions []string
+	for _, child := range r.actionElements(e) {
+		actions = append(actions, child.attr("name"))
+	}
+
+	// A single action is stored in Action, multiple ones in Actions
+	var action string
+	if len(actions) == 1 {
+		action, actions = actions[0], nil
+	}
+
+	for _, event := range events {
+		if event == "*" || strings.HasSuffix(event, ".*") {
+			r.unsupported(e, "event wildcards")
+		}
+
+		r.def.Transitions = append(r.def.Transitions, fsm.Transition{
+			FromState: from,
+			Event:     event,
+			ToState:   to,
+			Action:    action,
+			Actions:   actions,
+			Guard:     e.attr("cond"),
+			Metadata:  metadata,
+		})
+	}
+}
+
+// action returns the single action referenced by the executable content of a hook element.
+func (r *reader) action(e *element, current string) string {
+	action := current
+
+	for _, child := range r.actionElements(e) {
+		if action != "" {
+			r.unsupported(child, "multiple actions")
+		}
+
+		action = child.attr("name")
+	}
+
+	return action
+}
+
+// actionElements returns the elements referencing actions in the executable content of an element.
+func (r *reader) actionElements(e *element) []*element {
+	var actions []*element
+
+	for _, child := range e.children {
+		switch {
+		case child.name.Space == ActionNamespace && child.name.Local == "action":
+			if child.attr("name") == "" {
+				r.errorf(child, "action has no name")
+			}
+
+			actions = append(actions, child)
+
+		case child.name.Space == ActionNamespace && child.name.Local == "meta" && e.name.Local == "transition":
+
+		default:
+			r.unsupported(child, fmt.Sprintf("<%s> executable content", child.name.Local))
+		}
+	}
+
+	return actions
+}
+
+// target returns the single state of a target list.
+func (r *reader) target(e *element, targets string, feature string) string {
+	states := strings.Fields(targets)
+
+	if len(states) > 1 {
+		r.unsupported(e, feature)
+	}
+
+	if len(states) == 0 {
+		return ""
+	}
+
+	return states[0]
+}
+
+// first returns the first child state of an element.
+func (r *reader) first(e *element) string {
+	for _, child := range e.children {
+		if child.name.Space != Namespace {
+			continue
+		}
+
+		switch child.name.Local {
+		case "state", "parallel", "final":
+			return child.attr("id")
+		}
+	}
+
+	return ""
+}
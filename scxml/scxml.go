@@ -0,0 +1,56 @@
+// Package scxml converts state machine definitions from and to SCXML documents.
+//
+// SCXML (https://www.w3.org/TR/scxml/) states, parallel states, final states, history states
+// and transitions are mapped to their fsm.Definition counterparts.
+//
+// Actions (transition actions and state hooks) are referenced by name in executable content
+// using a custom element:
+//
+//	<scxml xmlns="http://www.w3.org/2005/07/scxml" xmlns:fsm="https://github.com/goph/fsm" version="1.0" initial="locked">
+//		<state id="locked">
+//			<transition event="insert_coin" target="unlocked" cond="valid_coin">
+//				<fsm:action name="unlock"/>
+//			</transition>
+//		</state>
+//		<state id="unlocked">
+//			<onentry>
+//				<fsm:action name="start_timer"/>
+//			</onentry>
+//			<transition event="push" target="locked"/>
+//		</state>
+//	</scxml>
+//
+// Transitions can reference multiple actions (executed in order), state hooks only a single one.
+//
+// Transition conditions are used as guard names and transition metadata is stored in <fsm:meta name="" value=""/> elements.
+//
+// Features which cannot be represented by the library
+// (eg. data models, scripts, invoked services, eventless and targetless transitions)
+// are reported as UnsupportedError.
+package scxml
+
+import (
+	"fmt"
+)
+
+const (
+	// Namespace is the namespace of SCXML elements.
+	Namespace = "http://www.w3.org/2005/07/scxml"
+
+	// ActionNamespace is the namespace of the custom executable content elements referencing actions.
+	ActionNamespace = "https://github.com/goph/fsm"
+)
+
+// UnsupportedError is returned when an SCXML document uses a feature the library cannot represent.
+type UnsupportedError struct {
+	// Feature describes the unsupported feature.
+	Feature string
+
+	// Line is the line of the document (starting from 1) the feature is used at.
+	Line int
+}
+
+// Error returns the formatted error message.
+func (e *UnsupportedError) Error() string {
+	return fmt.Sprintf("line %d: %s are not supported", e.Line, e.Feature)
+}
@@ -0,0 +1,230 @@
+package scxml_test
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/goph/fsm"
+	"github.com/goph/fsm/scxml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func order() fsm.Definition {
+	return fsm.Definition{
+		InitialState: "new",
+		States: []fsm.State{
+			{Name: "new"},
+			{Name: "in_fulfilment", Initial: "picking", OnEnter: "notify_warehouse"},
+			{Name: "picking", Parent: "in_fulfilment"},
+			{Name: "packing", Parent: "in_fulfilment", OnExit: "print_label"},
+			{Name: "in_fulfilment_history", Parent: "in_fulfilment", History: fsm.DeepHistory, Initial: "packing"},
+			{Name: "on_hold"},
+			{Name: "shipping", Parallel: true},
+			{Name: "tracking", Parent: "shipping"},
+			{Name: "invoicing", Parent: "shipping"},
+			{Name: "shipped", Final: true},
+			{Name: "cancelled", Final: true},
+		},
+		Transitions: []fsm.Transition{
+			{
+				FromState: "new",
+				Event:     "pay",
+				ToState:   "in_fulfilment",
+				Action:    "charge",
+				Guard:     "payment_valid",
+				Metadata:  map[string]string{"owner": "payments"},
+			},
+			{FromState: "in_fulfilment", Event: "cancel", ToState: "cancelled"},
+			{FromState: "in_fulfilment", Event: "abort", ToState: "cancelled"},
+			{FromState: "in_fulfilment", Event: "hold", ToState: "on_hold"},
+			{FromState: "picking", Event: "pick", ToState: "packing"},
+			{FromState: "packing", Event: "pack", ToState: "shipped", Actions: []string{"print_invoice", "notify_customer"}},
+			{FromState: "on_hold", Event: "resume", ToState: "in_fulfilment_history"},
+		},
+	}
+}
+
+func TestRead(t *testing.T) {
+	file, err := os.Open("testdata/order.scxml")
+	require.NoError(t, err)
+	defer file.Close()
+
+	def, err := scxml.Read(file)
+	require.NoError(t, err)
+
+	assert.Equal(t, order(), def)
+}
+
+func TestRead_DefaultInitialStates(t *testing.T) {
+	def, err := scxml.Read(strings.NewReader(`<scxml xmlns="http://www.w3.org/2005/07/scxml" version="1.0">
+	<state id="parent">
+		<state id="first"/>
+		<state id="second"/>
+	</state>
+	<state id="other">
+		<initial>
+			<transition target="child"/>
+		</initial>
+		<state id="child"/>
+	</state>
+</scxml>`))
+	require.NoError(t, err)
+
+	assert.Equal(t, "parent", def.InitialState)
+	assert.Equal(t, "first", def.State("parent").Initial)
+	assert.Equal(t, "child", def.State("other").Initial)
+}
+
+func TestRead_Unsupported(t *testing.T) {
+	tests := map[string]struct {
+		body    string
+		feature string
+	}{
+		"data model": {
+			body:    `<datamodel><data id="count" expr="0"/></datamodel>`,
+			feature: "data models",
+		},
+		"eventless transition": {
+			body:    `<state id="a"><transition target="b"/></state>`,
+			feature: "eventless transitions",
+		},
+		"targetless transition": {
+			body:    `<state id="a"><transition event="e"/></state>`,
+			feature: "targetless transitions",
+		},
+		"multiple targets": {
+			body:    `<state id="a"><transition event="e" target="b c"/></state>`,
+			feature: "multiple targets",
+		},
+		"executable content": {
+			body:    `<state id="a"><onentry><log expr="'entered'"/></onentry></state>`,
+			feature: "<log> executable content",
+		},
+		"multiple actions": {
+			body:    `<state id="a"><onentry><fsm:action name="a"/><fsm:action name="b"/></onentry></state>`,
+			feature: "multiple actions",
+		},
+		"invoke": {
+			body:    `<state id="a"><invoke type="http"/></state>`,
+			feature: "invoked services",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			_, err := scxml.Read(strings.NewReader(
+				`<scxml xmlns="http://www.w3.org/2005/07/scxml" xmlns:fsm="https://github.com/goph/fsm" version="1.0">` + "\n" +
+					test.body + "\n" +
+					`</scxml>`,
+			))
+			require.Error(t, err)
+
+			uerr, ok := err.(*scxml.UnsupportedError)
+			require.True(t, ok, err.Error())
+
+			assert.Equal(t, test.feature, uerr.Feature)
+			assert.Equal(t, 2, uerr.Line)
+		})
+	}
+}
+
+func TestRead_Invalid(t *testing.T) {
+	_, err := scxml.Read(strings.NewReader(`<statemachine/>`))
+
+	assert.EqualError(t, err, "line 1: root element is not an scxml element")
+}
+
+func TestWrite(t *testing.T) {
+	var buf bytes.Buffer
+
+	err := scxml.Write(&buf, order())
+	require.NoError(t, err)
+
+	if *update {
+		require.NoError(t, ioutil.WriteFile("testdata/order.scxml.golden", buf.Bytes(), 0644))
+	}
+
+	expected, err := ioutil.ReadFile("testdata/order.scxml.golden")
+	require.NoError(t, err)
+
+	assert.Equal(t, string(expected), buf.String())
+}
+
+func TestWrite_StateMachine(t *testing.T) {
+	sm := fsm.NewStateMachine(
+		nil,
+		[]fsm.Transition{
+			{FromState: "locked", Event: "coin", ToState: "unlocked", Action: "unlock"},
+			{FromState: "unlocked", Event: "push", ToState: "locked"},
+		},
+		fsm.WithInitialState("locked"),
+	)
+
+	var buf bytes.Buffer
+
+	err := scxml.Write(&buf, sm.Definition())
+	require.NoError(t, err)
+
+	def, err := scxml.Read(&buf)
+	require.NoError(t, err)
+
+	assert.Equal(t, sm.Definition().Transitions, def.Transitions)
+	assert.Equal(t, "locked", def.InitialState)
+}
+
+func TestWrite_Wildcards(t *testing.T) {
+	def := fsm.Definition{
+		InitialState: "locked",
+		Transitions: []fsm.Transition{
+			{FromState: fsm.AnyState, ExceptStates: []string{"broken"}, Event: "break", ToState: "broken"},
+			{FromStates: []string{"locked", "unlocked"}, Event: "inspect", ToState: "locked"},
+			{FromState: "unlocked", Event: "break", ToState: "locked"},
+		},
+		States: []fsm.State{{Name: "broken", Final: true}},
+	}
+
+	var buf bytes.Buffer
+
+	err := scxml.Write(&buf, def)
+	require.NoError(t, err)
+
+	read, err := scxml.Read(&buf)
+	require.NoError(t, err)
+
+	// Wildcard transitions are written after explicit ones, since they have lower precedence
+	assert.Equal(
+		t,
+		[]fsm.Transition{
+			{FromState: "locked", Event: "inspect", ToState: "locked"},
+			{FromState: "locked", Event: "break", ToState: "broken"},
+			{FromState: "unlocked", Event: "inspect", ToState: "locked"},
+			{FromState: "unlocked", Event: "break", ToState: "locked"},
+			{FromState: "unlocked", Event: "break", ToState: "broken"},
+		},
+		read.Transitions,
+	)
+}
+
+func TestWrite_Unrepresentable(t *testing.T) {
+	def := fsm.Definition{
+		States: []fsm.State{
+			{Name: "done", Final: true},
+		},
+		Transitions: []fsm.Transition{
+			{FromState: "done", Event: "restart", ToState: "new"},
+		},
+	}
+
+	err := scxml.Write(ioutil.Discard, def)
+
+	assert.EqualError(t, err, "final state \"done\" cannot have child states or transitions in SCXML")
+}
@@ -0,0 +1,222 @@
+package scxml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/goph/fsm"
+)
+
+// Write serializes a state machine definition as an SCXML document.
+//
+// Transitions with multiple source states (and wildcard transitions) are written to each of their source states.
+//
+// Definitions which cannot be represented in SCXML
+// (eg. final states with transitions or history states with hooks) are rejected.
+func Write(w io.Writer, def fsm.Definition) error {
+	wr := &writer{
+		def:         def,
+		children:    make(map[string][]string),
+		transitions: make(map[string][]fsm.Transition),
+	}
+
+	for _, state := range def.StateNames() {
+		// States in parent cycles would never be written
+		if def.IsAncestor(state, state) {
+			return fmt.Errorf("parents of state %q form a cycle", state)
+		}
+
+		parent := def.State(state).Parent
+		wr.children[parent] = append(wr.children[parent], state)
+	}
+
+	for _, t := range def.Transitions {
+		if (t.FromState == "" && len(t.FromStates) == 0) || t.Event == "" || t.ToState == "" {
+			return fmt.Errorf("transition from %q state triggered by %q event has empty fields", t.FromState, t.Event)
+		}
+	}
+
+	// Wildcard transitions come after explicit ones, since they have lower precedence
+	for _, wildcard := range []bool{false, true} {
+		for _, t := range def.Transitions {
+			if (t.FromState == fsm.AnyState) != wildcard {
+				continue
+			}
+
+			for _, source := range def.Sources(t) {
+				t := t
+				t.FromState = source
+				t.FromStates = nil
+				t.ExceptStates = nil
+
+				wr.transitions[source] = append(wr.transitions[source], t)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+
+	buf.WriteString(xml.Header)
+
+	buf.WriteString(`<scxml xmlns="` + Namespace + `" xmlns:fsm="` + ActionNamespace + `" version="1.0"`)
+	if def.InitialState != "" {
+		buf.WriteString(` initial="` + escape(def.InitialState) + `"`)
+	}
+	buf.WriteString(">\n")
+
+	if err := wr.writeStates(&buf, "", 1); err != nil {
+		return err
+	}
+
+	buf.WriteString("</scxml>\n")
+
+	_, err := buf.WriteTo(w)
+
+	return err
+}
+
+type writer struct {
+	def         fsm.Definition
+	children    map[string][]string
+	transitions map[string][]fsm.Transition
+}
+
+// writeStates writes the child states of a state recursively.
+func (w *writer) writeStates(buf *bytes.Buffer, parent string, depth int) error {
+	indent := strings.Repeat("\t", depth)
+
+	for _, name := range w.children[parent] {
+		state := w.def.State(name)
+
+		if state.History != "" {
+			if err := w.writeHistory(buf, state, indent); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		tag := "state"
+
+		switch {
+		case state.Final:
+			if len(w.children[name]) > 0 || len(w.transitions[name]) > 0 {
+				return fmt.Errorf("final state %q cannot have child states or transitions in SCXML", name)
+			}
+
+			tag = "final"
+
+		case state.Parallel:
+			tag = "parallel"
+		}
+
+		buf.WriteString(indent + "<" + tag + ` id="` + escape(name) + `"`)
+		if state.Initial != "" && !state.Parallel {
+			buf.WriteString(` initial="` + escape(state.Initial) + `"`)
+		}
+
+		if state.OnEnter == "" && state.OnExit == "" && len(w.transitions[name]) == 0 && len(w.children[name]) == 0 {
+			buf.WriteString("/>\n")
+
+			continue
+		}
+
+		buf.WriteString(">\n")
+
+		writeHook(buf, "onentry", state.OnEnter, indent+"\t")
+		writeHook(buf, "onexit", state.OnExit, indent+"\t")
+
+		for _, t := range w.transitions[name] {
+			writeTransition(buf, t, indent+"\t")
+		}
+
+		if err := w.writeStates(buf, name, depth+1); err != nil {
+			return err
+		}
+
+		buf.WriteString(indent + "</" + tag + ">\n")
+	}
+
+	return nil
+}
+
+// writeHistory writes a history pseudo-state.
+func (w *writer) writeHistory(buf *bytes.Buffer, state fsm.State, indent string) error {
+	if state.OnEnter != "" || state.OnExit != "" || len(w.transitions[state.Name]) > 0 || len(w.children[state.Name]) > 0 {
+		return fmt.Errorf("history state %q cannot have hooks, child states or transitions in SCXML", state.Name)
+	}
+
+	buf.WriteString(indent + `<history id="` + escape(state.Name) + `" type="` + escape(string(state.History)) + `"`)
+
+	if state.Initial == "" {
+		buf.WriteString("/>\n")
+
+		return nil
+	}
+
+	buf.WriteString(">\n")
+	buf.WriteString(indent + "\t" + `<transition target="` + escape(state.Initial) + `"/>` + "\n")
+	buf.WriteString(indent + "</history>\n")
+
+	return nil
+}
+
+// writeHook writes an onentry or onexit element referencing an action.
+func writeHook(buf *bytes.Buffer, tag string, action string, indent string) {
+	if action == "" {
+		return
+	}
+
+	buf.WriteString(indent + "<" + tag + ">\n")
+	buf.WriteString(indent + "\t" + `<fsm:action name="` + escape(action) + `"/>` + "\n")
+	buf.WriteString(indent + "</" + tag + ">\n")
+}
+
+// writeTransition writes a transition element.
+func writeTransition(buf *bytes.Buffer, t fsm.Transition, indent string) {
+	buf.WriteString(indent + `<transition event="` + escape(t.Event) + `" target="` + escape(t.ToState) + `"`)
+	if t.Guard != "" {
+		buf.WriteString(` cond="` + escape(t.Guard) + `"`)
+	}
+
+	if t.Action == "" && len(t.Actions) == 0 && len(t.Metadata) == 0 {
+		buf.WriteString("/>\n")
+
+		return
+	}
+
+	buf.WriteString(">\n")
+
+	for _, action := range append([]string{t.Action}, t.Actions...) {
+		if action != "" {
+			buf.WriteString(indent + "\t" + `<fsm:action name="` + escape(action) + `"/>` + "\n")
+		}
+	}
+
+	keys := make([]string, 0, len(t.Metadata))
+	for key := range t.Metadata {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		buf.WriteString(indent + "\t" + `<fsm:meta name="` + escape(key) + `" value="` + escape(t.Metadata[key]) + `"/>` + "\n")
+	}
+
+	buf.WriteString(indent + "</transition>\n")
+}
+
+// escape escapes a value for use in attributes.
+func escape(value string) string {
+	var buf bytes.Buffer
+
+	// Writing to a buffer never fails
+	_ = xml.EscapeText(&buf, []byte(value))
+
+	return buf.String()
+}
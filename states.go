@@ -0,0 +1,149 @@
+package fsm
+
+// State represents a state and the hooks executed when the state machine enters or leaves it.
+//
+// Hooks are actions handled by the delegate, just like transition actions.
+// Self transitions leave and enter the state again, so both hooks are executed.
+//
+// States can be nested into other (composite) states:
+// transitions declared on a parent state apply to all of its descendants.
+type State struct {
+	Name string
+
+	// OnEnter is the action executed whenever the state is entered.
+	OnEnter string
+
+	// OnExit is the action executed whenever the state is left.
+	OnExit string
+
+	// Parent is the name of the composite state this state is nested into.
+	Parent string
+
+	// Initial is the child state entered when a transition targets this (composite) state.
+	Initial string
+
+	// Parallel marks the children of this state as orthogonal regions.
+	//
+	// Entering a parallel state enters all of its regions at once.
+	Parallel bool
+
+	// History turns the state into a history pseudo-state of its parent.
+	//
+	// Targeting a history pseudo-state enters the child of the parent which was active when the parent was left.
+	// Without recorded history the Initial state of the pseudo-state (or the parent) is entered.
+	History HistoryType
+
+	// Final marks the state as a final state: final states are not expected to be left.
+	Final bool
+}
+
+// WithStates declares states, their entry and exit hooks and their hierarchy.
+//
+// States don't have to be declared unless they have hooks or they are part of a hierarchy.
+func WithStates(states []State) Option {
+	return func(sm *StateMachine) {
+		for _, state := range states {
+			if _, ok := sm.states[state.Name]; !ok {
+				sm.stateNames = append(sm.stateNames, state.Name)
+			}
+
+			sm.add(state)
+
+			if state.History != "" {
+				sm.hasHistory[state.Parent] = true
+			}
+		}
+	}
+}
+
+// WithInitialState declares the initial state of the state machine.
+//
+// The state machine doesn't enforce it, but it is used to validate the definition.
+func WithInitialState(state string) Option {
+	return func(sm *StateMachine) {
+		sm.initialState = state
+	}
+}
+
+// Path returns the path of a state from the outermost ancestor to the state itself.
+func (sm *StateMachine) Path(state string) []string {
+	ancestors := sm.ancestors(state)
+
+	path := make([]string, len(ancestors))
+	for i, ancestor := range ancestors {
+		path[len(ancestors)-1-i] = ancestor
+	}
+
+	return path
+}
+
+// eachAction calls a function with the actions executed during a transition in order:
+// exit hooks from the innermost states, the transition actions, then enter hooks from the outermost states.
+// The function is told whether the action is a state hook. The iteration stops when the function returns false.
+//
+// Only states below the transition domain are left and entered.
+// It doesn't allocate, so that triggers can be allocation free.
+func (sm *StateMachine) eachAction(exited []string, t *Transition, targets []string, domain string, fn func(action string, hook bool) bool) {
+	for i, leaf := range exited {
+		state := leaf
+
+		for depth := 0; state != domain && state != "" && depth <= len(sm.states); depth++ {
+			// States shared with subsequently left leaves (eg. parallel states) are left with the last one,
+			// so that they are left after all of their descendants
+			if sm.covers(exited[i+1:], state) {
+				break
+			}
+
+			if onExit := sm.states[state].OnExit; onExit != "" {
+				if !fn(onExit, true) {
+					return
+				}
+			}
+
+			state = sm.states[state].Parent
+		}
+	}
+
+	if t.Action != "" && !fn(t.Action, false) {
+		return
+	}
+
+	for _, action := range t.Actions {
+		if action != "" && !fn(action, false) {
+			return
+		}
+	}
+
+	for i, leaf := range targets {
+		var depth int
+
+		for state := leaf; state != domain && state != "" && depth <= len(sm.states); depth++ {
+			state = sm.states[state].Parent
+		}
+
+		// States are entered from the outermost one
+		for ; depth > 0; depth-- {
+			state := leaf
+			for j := 1; j < depth; j++ {
+				state = sm.states[state].Parent
+			}
+
+			if onEnter := sm.states[state].OnEnter; onEnter != "" && !sm.covers(targets[:i], state) {
+				if !fn(onEnter, true) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// containsState checks whether a state is in a list of states.
+func containsState(states []string, state string) bool {
+	for _, s := range states {
+		if s == state {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,289 @@
+package fsm_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/goph/fsm"
+	"github.com/goph/fsm/internal/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStateMachine_StateHooks(t *testing.T) {
+	delegate := new(mocks.Delegate)
+	transitions := []fsm.Transition{
+		{
+			FromState: "current_state",
+			Event:     "event",
+			ToState:   "next_state",
+			Action:    "action",
+		},
+	}
+	states := []fsm.State{
+		{
+			Name:    "current_state",
+			OnEnter: "enter_current_state",
+			OnExit:  "exit_current_state",
+		},
+		{
+			Name:    "next_state",
+			OnEnter: "enter_next_state",
+			OnExit:  "exit_next_state",
+		},
+	}
+
+	calls := new(recorder)
+
+	delegate.On("Handle", mock.Anything, "current_state", "next_state", []interface{}{"argument"}).Return(calls.record)
+
+	sm := fsm.NewStateMachine(delegate, transitions, fsm.WithStates(states))
+
+	err := sm.Trigger("current_state", "event", "argument")
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"exit_current_state", "action", "enter_next_state"}, calls.actions)
+}
+
+func TestStateMachine_StateHookError(t *testing.T) {
+	delegate := new(mocks.Delegate)
+	transitions := []fsm.Transition{
+		{
+			FromState: "current_state",
+			Event:     "event",
+			ToState:   "next_state",
+			Action:    "action",
+		},
+	}
+	states := []fsm.State{
+		{
+			Name:   "current_state",
+			OnExit: "exit_current_state",
+		},
+	}
+
+	hookErr := errors.New("error happened")
+
+	delegate.On("Handle", "exit_current_state", "current_state", "next_state", []interface{}{"argument"}).Return(hookErr)
+
+	sm := fsm.NewStateMachine(delegate, transitions, fsm.WithStates(states))
+
+	err := sm.Trigger("current_state", "event", "argument")
+
+	require.Error(t, err)
+
+	derr := err.(*fsm.DelegateError)
+
+	assert.Equal(t, hookErr, derr.Cause())
+	assert.Equal(t, "exit_current_state", derr.Action())
+
+	delegate.AssertNotCalled(t, "Handle", "action", "current_state", "next_state", []interface{}{"argument"})
+}
+
+func TestStateMachine_StateHooks_StopPropagation(t *testing.T) {
+	delegate := new(mocks.Delegate)
+	transitions := []fsm.Transition{
+		{
+			FromState: "current_state",
+			Event:     "event",
+			ToState:   "next_state",
+			Action:    "action",
+			Actions:   []string{"other_action"},
+		},
+	}
+	states := []fsm.State{
+		{
+			Name:   "current_state",
+			OnExit: "exit_current_state",
+		},
+		{
+			Name:    "next_state",
+			OnEnter: "enter_next_state",
+		},
+	}
+
+	calls := new(recorder)
+
+	delegate.On("Handle", "action", "current_state", "next_state", []interface{}{"argument"}).Return(fsm.StopPropagation)
+	delegate.On("Handle", mock.Anything, "current_state", "next_state", []interface{}{"argument"}).Return(calls.record)
+
+	sm := fsm.NewStateMachine(delegate, transitions, fsm.WithStates(states))
+
+	err := sm.Trigger("current_state", "event", "argument")
+
+	// Only the remaining transition actions are skipped
+	require.NoError(t, err)
+	assert.Equal(t, []string{"exit_current_state", "enter_next_state"}, calls.actions)
+}
+
+// recorder records the actions handled by a mock delegate.
+type recorder struct {
+	actions []string
+}
+
+// record can be returned by a mock delegate to record the handled action.
+func (r *recorder) record(action string, fromState string, toState string, args []interface{}) error {
+	r.actions = append(r.actions, action)
+
+	return nil
+}
+
+// orderStates returns a hierarchy of states where in_fulfilment has two children.
+func orderStates() []fsm.State {
+	return []fsm.State{
+		{
+			Name:    "in_fulfilment",
+			Initial: "picking",
+			OnEnter: "enter_in_fulfilment",
+			OnExit:  "exit_in_fulfilment",
+		},
+		{
+			Name:    "picking",
+			Parent:  "in_fulfilment",
+			OnEnter: "enter_picking",
+			OnExit:  "exit_picking",
+		},
+		{
+			Name:    "packing",
+			Parent:  "in_fulfilment",
+			OnEnter: "enter_packing",
+			OnExit:  "exit_packing",
+		},
+		{
+			Name:    "cancelled",
+			OnEnter: "enter_cancelled",
+		},
+	}
+}
+
+func TestStateMachine_ParentTransition(t *testing.T) {
+	delegate := new(mocks.Delegate)
+	transitions := []fsm.Transition{
+		{
+			FromState: "in_fulfilment",
+			Event:     "cancel",
+			ToState:   "cancelled",
+			Action:    "cancel",
+		},
+	}
+
+	calls := new(recorder)
+
+	delegate.On("Handle", mock.Anything, "packing", "cancelled", []interface{}{"argument"}).Return(calls.record)
+
+	sm := fsm.NewStateMachine(delegate, transitions, fsm.WithStates(orderStates()))
+
+	err := sm.Trigger("packing", "cancel", "argument")
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"exit_packing", "exit_in_fulfilment", "cancel", "enter_cancelled"}, calls.actions)
+}
+
+func TestStateMachine_ParentTransition_Guard(t *testing.T) {
+	delegate := new(mocks.Delegate)
+	transitions := []fsm.Transition{
+		{
+			FromState: "in_fulfilment",
+			Event:     "cancel",
+			ToState:   "cancelled",
+			Guard:     "refundable",
+		},
+	}
+
+	guard := new(mocks.Guard)
+
+	// Guards see the current state, just like delegates
+	guard.On("Check", "refundable", "packing", "cancelled", []interface{}{"argument"}).Return(true)
+	delegate.On("Handle", mock.Anything, "packing", "cancelled", []interface{}{"argument"}).Return(nil)
+
+	sm := fsm.NewStateMachine(delegate, transitions, fsm.WithStates(orderStates()), fsm.WithGuard(guard))
+
+	err := sm.Trigger("packing", "cancel", "argument")
+
+	require.NoError(t, err)
+
+	guard.AssertExpectations(t)
+}
+
+func TestStateMachine_InnermostTransition(t *testing.T) {
+	delegate := new(mocks.Delegate)
+	transitions := []fsm.Transition{
+		{
+			FromState: "in_fulfilment",
+			Event:     "cancel",
+			ToState:   "cancelled",
+			Action:    "cancel",
+		},
+		{
+			FromState: "packing",
+			Event:     "cancel",
+			ToState:   "picking",
+			Action:    "unpack",
+		},
+	}
+
+	calls := new(recorder)
+
+	delegate.On("Handle", mock.Anything, "packing", "picking", []interface{}{"argument"}).Return(calls.record)
+
+	sm := fsm.NewStateMachine(delegate, transitions, fsm.WithStates(orderStates()))
+
+	err := sm.Trigger("packing", "cancel", "argument")
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"exit_packing", "unpack", "enter_picking"}, calls.actions)
+}
+
+func TestStateMachine_InitialState(t *testing.T) {
+	delegate := new(mocks.Delegate)
+	transitions := []fsm.Transition{
+		{
+			FromState: "cancelled",
+			Event:     "reopen",
+			ToState:   "in_fulfilment",
+			Action:    "reopen",
+		},
+	}
+
+	calls := new(recorder)
+
+	delegate.On("Handle", mock.Anything, "cancelled", "picking", []interface{}{"argument"}).Return(calls.record)
+
+	sm := fsm.NewStateMachine(delegate, transitions, fsm.WithStates(orderStates()))
+
+	err := sm.Trigger("cancelled", "reopen", "argument")
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"reopen", "enter_in_fulfilment", "enter_picking"}, calls.actions)
+}
+
+func TestStateMachine_Path(t *testing.T) {
+	sm := fsm.NewStateMachine(new(mocks.Delegate), nil, fsm.WithStates(orderStates()))
+
+	assert.Equal(t, []string{"in_fulfilment", "packing"}, sm.Path("packing"))
+	assert.Equal(t, []string{"cancelled"}, sm.Path("cancelled"))
+}
+
+func TestDefinition_Hierarchy(t *testing.T) {
+	def := fsm.Definition{
+		States: append(
+			orderStates(),
+			fsm.State{Name: "in_fulfilment.history", Parent: "in_fulfilment", History: fsm.ShallowHistory},
+		),
+	}
+
+	assert.Equal(t, []string{"in_fulfilment"}, def.Ancestors("packing"))
+	assert.Empty(t, def.Ancestors("cancelled"))
+	assert.True(t, def.IsAncestor("in_fulfilment", "packing"))
+	assert.False(t, def.IsAncestor("packing", "packing"))
+	assert.Equal(t, []string{"picking", "packing"}, def.Children("in_fulfilment"))
+
+	assert.Equal(t, []string{"picking"}, def.Targets("in_fulfilment"))
+
+	// Without a default state the parent of the history pseudo-state is entered
+	assert.Equal(t, []string{"picking"}, def.Targets("in_fulfilment.history"))
+
+	assert.Equal(t, "in_fulfilment", def.Domain("picking", "packing"))
+	assert.Equal(t, "", def.Domain("packing", "cancelled"))
+}
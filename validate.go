@@ -0,0 +1,562 @@
+package fsm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ActionEnumerator is implemented by delegates which know every action they handle.
+//
+// It allows validating that every action in a definition is handled.
+type ActionEnumerator interface {
+	// Actions returns the actions handled by the delegate.
+	Actions() []string
+}
+
+// GuardEnumerator is implemented by guards which know every guard they evaluate.
+//
+// It allows validating that every guard in a definition is known.
+type GuardEnumerator interface {
+	// Guards returns the names of the guards evaluated by the guard.
+	Guards() []string
+}
+
+// ProblemKind categorizes definition problems.
+type ProblemKind string
+
+const (
+	// EmptyField is reported for transitions and states with missing required fields.
+	EmptyField ProblemKind = "empty_field"
+
+	// AmbiguousTransition is reported for transitions which can never be selected,
+	// because an earlier transition for the same state-event pair always wins.
+	AmbiguousTransition ProblemKind = "ambiguous_transition"
+
+	// UnknownAction is reported for actions the delegate doesn't handle.
+	UnknownAction ProblemKind = "unknown_action"
+
+	// UnknownGuard is reported for guards that cannot be evaluated.
+	UnknownGuard ProblemKind = "unknown_guard"
+
+	// InvalidHierarchy is reported for invalid state hierarchies (eg. unknown parents or cycles).
+	InvalidHierarchy ProblemKind = "invalid_hierarchy"
+
+	// UnreachableState is reported for states which cannot be reached from the initial state.
+	UnreachableState ProblemKind = "unreachable_state"
+
+	// DeadEndState is reported for non-final states which cannot be left.
+	DeadEndState ProblemKind = "dead_end_state"
+
+	// InvalidWildcard is reported for transitions mixing wildcard and explicit source states.
+	InvalidWildcard ProblemKind = "invalid_wildcard"
+
+	// DuplicateState is reported for states declared more than once.
+	DuplicateState ProblemKind = "duplicate_state"
+)
+
+// Problem describes a single problem of a state machine definition.
+type Problem struct {
+	Kind ProblemKind
+
+	// Transition is the index of the transition the problem relates to or -1.
+	Transition int
+
+	// State is the name of the state the problem relates to if any.
+	State string
+
+	Message string
+}
+
+// String returns the problem message.
+func (p Problem) String() string {
+	return p.Message
+}
+
+// ValidationError is returned when a state machine definition is invalid.
+type ValidationError struct {
+	problems []Problem
+}
+
+// Problems returns every problem found in the definition.
+func (e *ValidationError) Problems() []Problem {
+	return e.problems
+}
+
+// Error returns the formatted error message.
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.problems))
+	for i, problem := range e.problems {
+		messages[i] = problem.Message
+	}
+
+	return fmt.Sprintf("invalid state machine definition: %s", strings.Join(messages, "; "))
+}
+
+// NewStateMachineStrict returns a new StateMachine or an error if the definition is invalid.
+//
+// See StateMachine.Validate for details.
+func NewStateMachineStrict(delegate Delegate, transitions []Transition, opts ...Option) (*StateMachine, error) {
+	sm := NewStateMachine(delegate, transitions, opts...)
+
+	if err := sm.Validate(); err != nil {
+		return nil, err
+	}
+
+	return sm, nil
+}
+
+// Validate checks the definition of the state machine and reports all problems at once in a ValidationError.
+//
+// The following problems are reported:
+// empty fields, duplicate states, ambiguous transitions, invalid state hierarchies,
+// guards without a guard to evaluate them, unknown guards (if the guard is a GuardEnumerator),
+// unknown actions (if the delegate is an ActionEnumerator),
+// states unreachable from the initial state (if there is one) and dead-end non-final states.
+func (sm *StateMachine) Validate() error {
+	var problems []Problem
+
+	problems = append(problems, sm.validateFields()...)
+	problems = append(problems, sm.validateHierarchy()...)
+	problems = append(problems, sm.validateAmbiguity()...)
+	problems = append(problems, sm.validateGuards()...)
+	problems = append(problems, sm.validateActions()...)
+	problems = append(problems, sm.validateReachability()...)
+	problems = append(problems, sm.validateDeadEnds()...)
+
+	if len(problems) == 0 {
+		return nil
+	}
+
+	return &ValidationError{problems}
+}
+
+// allStates returns every state of the definition in declaration order.
+func (sm *StateMachine) allStates() []string {
+	return sm.Definition().StateNames()
+}
+
+// validateFields checks that required fields are not empty.
+func (sm *StateMachine) validateFields() []Problem {
+	var problems []Problem
+
+	for _, name := range sm.stateNames {
+		if name == "" {
+			problems = append(problems, Problem{
+				Kind:       EmptyField,
+				Transition: -1,
+				Message:    "state has no name",
+			})
+		}
+
+		if sm.duplicates[name] {
+			problems = append(problems, Problem{
+				Kind:       DuplicateState,
+				Transition: -1,
+				State:      name,
+				Message:    fmt.Sprintf("state %q is declared more than once", name),
+			})
+		}
+	}
+
+	for i, t := range sm.transitions {
+		fields := []struct {
+			name  string
+			empty bool
+		}{
+			{"from state", t.FromState == "" && len(t.FromStates) == 0},
+			{"event", t.Event == ""},
+			{"to state", t.ToState == ""},
+		}
+
+		for _, field := range fields {
+			if field.empty {
+				problems = append(problems, Problem{
+					Kind:       EmptyField,
+					Transition: i,
+					State:      t.FromState,
+					Message:    fmt.Sprintf("transition %d has no %s", i, field.name),
+				})
+			}
+		}
+
+		if t.FromState == AnyState && len(t.FromStates) > 0 {
+			problems = append(problems, Problem{
+				Kind:       InvalidWildcard,
+				Transition: i,
+				State:      t.FromState,
+				Message:    fmt.Sprintf("wildcard transition %d has additional from states", i),
+			})
+		}
+
+		if t.FromState != AnyState && len(t.ExceptStates) > 0 {
+			problems = append(problems, Problem{
+				Kind:       InvalidWildcard,
+				Transition: i,
+				State:      t.FromState,
+				Message:    fmt.Sprintf("transition %d has except states, but it is not a wildcard transition", i),
+			})
+		}
+	}
+
+	return problems
+}
+
+// validateHierarchy checks that parents and initial states exist and there are no cycles.
+func (sm *StateMachine) validateHierarchy() []Problem {
+	var problems []Problem
+
+	add := func(state string, format string, args ...interface{}) {
+		problems = append(problems, Problem{
+			Kind:       InvalidHierarchy,
+			Transition: -1,
+			State:      state,
+			Message:    fmt.Sprintf(format, args...),
+		})
+	}
+
+	for _, name := range sm.stateNames {
+		state := sm.states[name]
+
+		if state.Parent != "" {
+			if _, ok := sm.states[state.Parent]; !ok {
+				add(name, "parent %q of state %q is not declared", state.Parent, name)
+			}
+		}
+
+		if state.History != "" {
+			if state.Parent == "" {
+				add(name, "history state %q has no parent", name)
+			}
+
+			if state.History != ShallowHistory && state.History != DeepHistory {
+				add(name, "history state %q has unknown history type %q", name, state.History)
+			}
+		}
+
+		// The initial state of a history pseudo-state is its default state: a child of its parent
+		if state.Initial != "" && state.History != "" && sm.states[state.Initial].Parent != state.Parent {
+			add(name, "default state %q of history state %q is not a child of its parent", state.Initial, name)
+		}
+
+		if state.Initial != "" && state.History == "" && sm.states[state.Initial].Parent != name {
+			add(name, "initial state %q of state %q is not its child", state.Initial, name)
+		}
+
+		if state.Parallel && len(sm.children[name]) == 0 {
+			add(name, "parallel state %q has no regions", name)
+		}
+
+		seen := map[string]bool{name: true}
+		for parent := state.Parent; parent != ""; parent = sm.states[parent].Parent {
+			if seen[parent] {
+				add(name, "parents of state %q form a cycle", name)
+
+				break
+			}
+
+			seen[parent] = true
+		}
+	}
+
+	return problems
+}
+
+// validateAmbiguity checks that every transition can be selected.
+func (sm *StateMachine) validateAmbiguity() []Problem {
+	var problems []Problem
+
+	for i := range sm.transitions {
+		t := &sm.transitions[i]
+
+		if t.FromState == AnyState {
+			if other := sm.shadowingWildcard(t); other != nil {
+				problems = append(problems, sm.shadowedProblem(i, AnyState, other))
+			}
+
+			continue
+		}
+
+		for _, source := range sources(t) {
+			if other := shadowing(t, sm.findTransitions(source, t.Event)); other != nil {
+				problems = append(problems, sm.shadowedProblem(i, source, other))
+
+				break
+			}
+		}
+	}
+
+	return problems
+}
+
+// shadowing returns the transition declared before a transition which is always selected instead of it (if any).
+func shadowing(t *Transition, transitions []*compiledTransition) *Transition {
+	for _, ct := range transitions {
+		other := ct.transition
+		if other == t {
+			break
+		}
+
+		if other.Guard == "" || other.Guard == t.Guard {
+			return other
+		}
+	}
+
+	return nil
+}
+
+// shadowingWildcard returns the wildcard transition declared before a wildcard transition
+// which is always selected instead of it (if any).
+//
+// The earlier transition has to apply to every state the later one applies to.
+func (sm *StateMachine) shadowingWildcard(t *Transition) *Transition {
+	var candidates []*compiledTransition
+
+	for _, ct := range sm.wildcards[t.Event] {
+		if ct.transition == t {
+			break
+		}
+
+		// The earlier transition must not exclude states the later one applies to
+		var narrower bool
+		for _, except := range ct.transition.ExceptStates {
+			if !sm.excludes(t, except) {
+				narrower = true
+
+				break
+			}
+		}
+
+		if !narrower {
+			candidates = append(candidates, ct)
+		}
+	}
+
+	return shadowing(t, candidates)
+}
+
+// shadowedProblem returns the problem of a transition (from a source state) shadowed by another one.
+func (sm *StateMachine) shadowedProblem(i int, source string, other *Transition) Problem {
+	t := sm.transitions[i]
+
+	return Problem{
+		Kind:       AmbiguousTransition,
+		Transition: i,
+		State:      t.FromState,
+		Message: fmt.Sprintf(
+			"transition %d from %q state triggered by %q event is shadowed by transition %d",
+			i,
+			source,
+			t.Event,
+			sm.transitionIndex(other),
+		),
+	}
+}
+
+// transitionIndex returns the index of a transition of the state machine.
+func (sm *StateMachine) transitionIndex(t *Transition) int {
+	for i := range sm.transitions {
+		if &sm.transitions[i] == t {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// validateGuards checks that guards can be evaluated.
+func (sm *StateMachine) validateGuards() []Problem {
+	var problems []Problem
+
+	var known map[string]bool
+
+	if enumerator, ok := sm.guard.(GuardEnumerator); ok {
+		known = make(map[string]bool)
+
+		for _, guard := range enumerator.Guards() {
+			known[guard] = true
+		}
+	}
+
+	for i, t := range sm.transitions {
+		if t.Guard == "" {
+			continue
+		}
+
+		if sm.guard == nil {
+			problems = append(problems, Problem{
+				Kind:       UnknownGuard,
+				Transition: i,
+				State:      t.FromState,
+				Message:    fmt.Sprintf("transition %d has guard %q, but there is no guard to evaluate it", i, t.Guard),
+			})
+
+			continue
+		}
+
+		if known != nil && !known[t.Guard] {
+			problems = append(problems, Problem{
+				Kind:       UnknownGuard,
+				Transition: i,
+				State:      t.FromState,
+				Message:    fmt.Sprintf("transition %d has unknown guard %q", i, t.Guard),
+			})
+		}
+	}
+
+	return problems
+}
+
+// validateActions checks that the delegate handles every action if it can enumerate its actions.
+func (sm *StateMachine) validateActions() []Problem {
+	enumerator, ok := sm.delegate.(ActionEnumerator)
+	if !ok {
+		return nil
+	}
+
+	known := make(map[string]bool)
+	for _, action := range enumerator.Actions() {
+		known[action] = true
+	}
+
+	var problems []Problem
+
+	for i, t := range sm.transitions {
+		for _, action := range append([]string{t.Action}, t.Actions...) {
+			if action != "" && !known[action] {
+				problems = append(problems, Problem{
+					Kind:       UnknownAction,
+					Transition: i,
+					State:      t.FromState,
+					Message:    fmt.Sprintf("transition %d has unknown action %q", i, action),
+				})
+			}
+		}
+	}
+
+	for _, name := range sm.stateNames {
+		state := sm.states[name]
+
+		for _, hook := range []string{state.OnEnter, state.OnExit} {
+			if hook != "" && !known[hook] {
+				problems = append(problems, Problem{
+					Kind:       UnknownAction,
+					Transition: -1,
+					State:      name,
+					Message:    fmt.Sprintf("state %q has unknown hook action %q", name, hook),
+				})
+			}
+		}
+	}
+
+	return problems
+}
+
+// validateReachability checks that every state can be reached from the initial state.
+func (sm *StateMachine) validateReachability() []Problem {
+	if sm.initialState == "" {
+		return nil
+	}
+
+	reached := sm.reachableStates(sm.initialState)
+
+	var problems []Problem
+
+	for _, state := range sm.allStates() {
+		// History pseudo-states are never active
+		if reached[state] || sm.states[state].History != "" {
+			continue
+		}
+
+		problems = append(problems, Problem{
+			Kind:       UnreachableState,
+			Transition: -1,
+			State:      state,
+			Message:    fmt.Sprintf("state %q is unreachable from initial state %q", state, sm.initialState),
+		})
+	}
+
+	return problems
+}
+
+// reachableStates returns every state which can be active after entering a state.
+func (sm *StateMachine) reachableStates(state string) map[string]bool {
+	def := sm.Definition()
+
+	transitions := make(map[string][]Transition)
+	for _, t := range sm.transitions {
+		for _, source := range def.Sources(t) {
+			transitions[source] = append(transitions[source], t)
+		}
+	}
+
+	reached := make(map[string]bool)
+
+	var queue []string
+
+	enter := func(state string) {
+		for _, leaf := range sm.resolveTargets(state, nil) {
+			for _, s := range sm.ancestors(leaf) {
+				if !reached[s] {
+					reached[s] = true
+					queue = append(queue, s)
+				}
+			}
+		}
+	}
+
+	enter(state)
+
+	for len(queue) > 0 {
+		state := queue[0]
+		queue = queue[1:]
+
+		for _, t := range transitions[state] {
+			enter(t.ToState)
+		}
+	}
+
+	return reached
+}
+
+// validateDeadEnds checks that every non-final leaf state can be left.
+func (sm *StateMachine) validateDeadEnds() []Problem {
+	def := sm.Definition()
+
+	leaving := make(map[string]bool)
+	for _, t := range sm.transitions {
+		for _, source := range def.Sources(t) {
+			leaving[source] = true
+		}
+	}
+
+	var problems []Problem
+
+	for _, state := range sm.allStates() {
+		s := sm.states[state]
+
+		// Only leaf states can be active
+		if s.Final || s.History != "" || s.Parallel || s.Initial != "" || len(sm.children[state]) > 0 {
+			continue
+		}
+
+		var left bool
+		for _, ancestor := range sm.ancestors(state) {
+			if leaving[ancestor] {
+				left = true
+
+				break
+			}
+		}
+
+		if !left {
+			problems = append(problems, Problem{
+				Kind:       DeadEndState,
+				Transition: -1,
+				State:      state,
+				Message:    fmt.Sprintf("state %q cannot be left, but it is not final", state),
+			})
+		}
+	}
+
+	return problems
+}
@@ -0,0 +1,333 @@
+package fsm_test
+
+import (
+	"testing"
+
+	"github.com/goph/fsm"
+	"github.com/goph/fsm/internal/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// problemKinds returns the kinds and the subjects of the reported problems for easier comparison.
+func problemKinds(t *testing.T, err error) []string {
+	require.IsType(t, &fsm.ValidationError{}, err)
+
+	var kinds []string
+	for _, problem := range err.(*fsm.ValidationError).Problems() {
+		kinds = append(kinds, string(problem.Kind)+" "+problem.State)
+	}
+
+	return kinds
+}
+
+func TestNewStateMachineStrict(t *testing.T) {
+	delegate := fsm.NewActionMuxDelegate(map[string]fsm.Delegate{
+		"coin": new(mocks.Delegate),
+		"pass": new(mocks.Delegate),
+	})
+	transitions := []fsm.Transition{
+		{
+			FromState: "locked",
+			Event:     "coin_inserted",
+			ToState:   "unlocked",
+			Action:    "coin",
+		},
+		{
+			FromState: "unlocked",
+			Event:     "pushed",
+			ToState:   "locked",
+			Action:    "pass",
+		},
+	}
+
+	sm, err := fsm.NewStateMachineStrict(delegate, transitions, fsm.WithInitialState("locked"))
+
+	require.NoError(t, err)
+	assert.NotNil(t, sm)
+}
+
+func TestNewStateMachineStrict_Invalid(t *testing.T) {
+	delegate := fsm.NewActionMuxDelegate(map[string]fsm.Delegate{
+		"coin": new(mocks.Delegate),
+	})
+	transitions := []fsm.Transition{
+		{
+			FromState: "locked",
+			Event:     "coin_inserted",
+			ToState:   "unlocked",
+			Action:    "coin",
+		},
+		{
+			FromState: "locked",
+			Event:     "coin_inserted",
+			ToState:   "locked",
+			Action:    "refund",
+		},
+		{
+			FromState: "broken",
+			Event:     "",
+			ToState:   "locked",
+		},
+	}
+
+	sm, err := fsm.NewStateMachineStrict(delegate, transitions, fsm.WithInitialState("locked"))
+
+	require.Error(t, err)
+	assert.Nil(t, sm)
+
+	assert.Equal(
+		t,
+		[]string{
+			"empty_field broken",
+			"ambiguous_transition locked",
+			"unknown_action locked",
+			"unreachable_state broken",
+			"dead_end_state unlocked",
+		},
+		problemKinds(t, err),
+	)
+
+	problems := err.(*fsm.ValidationError).Problems()
+
+	assert.Equal(t, 2, problems[0].Transition)
+	assert.Equal(t, 1, problems[1].Transition)
+	assert.Equal(t, -1, problems[3].Transition)
+	assert.EqualError(
+		t,
+		err,
+		"invalid state machine definition: "+
+			"transition 2 has no event; "+
+			"transition 1 from \"locked\" state triggered by \"coin_inserted\" event is shadowed by transition 0; "+
+			"transition 1 has unknown action \"refund\"; "+
+			"state \"broken\" is unreachable from initial state \"locked\"; "+
+			"state \"unlocked\" cannot be left, but it is not final",
+	)
+}
+
+func TestStateMachine_Validate_Actions(t *testing.T) {
+	delegate := fsm.NewActionMuxDelegate(map[string]fsm.Delegate{
+		"charge_card": new(mocks.Delegate),
+	})
+	transitions := []fsm.Transition{
+		{
+			FromState: "new",
+			Event:     "pay",
+			ToState:   "paid",
+			Actions:   []string{"charge_card", "send_receipt"},
+		},
+		{
+			FromState: "paid",
+			Event:     "archive",
+			ToState:   "new",
+		},
+	}
+
+	sm := fsm.NewStateMachine(delegate, transitions)
+
+	err := sm.Validate()
+
+	require.Error(t, err)
+	assert.EqualError(t, err, "invalid state machine definition: transition 0 has unknown action \"send_receipt\"")
+}
+
+func TestStateMachine_Validate_Guards(t *testing.T) {
+	transitions := []fsm.Transition{
+		{
+			FromState: "locked",
+			Event:     "coin_inserted",
+			ToState:   "unlocked",
+			Guard:     "valid_coin",
+		},
+		{
+			FromState: "locked",
+			Event:     "coin_inserted",
+			ToState:   "locked",
+			Guard:     "invalid_coin",
+		},
+		{
+			FromState: "unlocked",
+			Event:     "pushed",
+			ToState:   "locked",
+		},
+	}
+
+	sm := fsm.NewStateMachine(new(mocks.Delegate), transitions)
+
+	assert.Equal(t, []string{"unknown_guard locked", "unknown_guard locked"}, problemKinds(t, sm.Validate()))
+
+	guard := fsm.NewGuardMux(map[string]fsm.Guard{
+		"valid_coin": new(mocks.Guard),
+	})
+
+	sm = fsm.NewStateMachine(new(mocks.Delegate), transitions, fsm.WithGuard(guard))
+
+	assert.Equal(t, []string{"unknown_guard locked"}, problemKinds(t, sm.Validate()))
+}
+
+func TestStateMachine_Validate_Hierarchy(t *testing.T) {
+	states := []fsm.State{
+		{
+			Name:    "in_fulfilment",
+			Initial: "cancelled",
+		},
+		{
+			Name:   "picking",
+			Parent: "in_progress",
+		},
+		{
+			Name:   "loop",
+			Parent: "other_loop",
+		},
+		{
+			Name:   "other_loop",
+			Parent: "loop",
+		},
+		{
+			Name:  "cancelled",
+			Final: true,
+		},
+	}
+	transitions := []fsm.Transition{
+		{
+			FromState: "in_fulfilment",
+			Event:     "cancel",
+			ToState:   "cancelled",
+		},
+		{
+			FromState: "loop",
+			Event:     "cancel",
+			ToState:   "cancelled",
+		},
+	}
+
+	sm := fsm.NewStateMachine(new(mocks.Delegate), transitions, fsm.WithStates(states))
+
+	assert.Equal(
+		t,
+		[]string{
+			"invalid_hierarchy in_fulfilment",
+			"invalid_hierarchy picking",
+			"invalid_hierarchy loop",
+			"invalid_hierarchy other_loop",
+			"dead_end_state picking",
+		},
+		problemKinds(t, sm.Validate()),
+	)
+}
+
+func TestStateMachine_Validate_History(t *testing.T) {
+	transitions := []fsm.Transition{
+		{
+			FromState: "new",
+			Event:     "place",
+			ToState:   "in_fulfilment",
+		},
+		{
+			FromState: "picking",
+			Event:     "pick",
+			ToState:   "packing",
+		},
+		{
+			FromState: "boxing",
+			Event:     "box",
+			ToState:   "labelling",
+		},
+		{
+			FromState: "in_fulfilment",
+			Event:     "hold",
+			ToState:   "on_hold",
+		},
+		{
+			FromState: "on_hold",
+			Event:     "resume",
+			ToState:   "in_fulfilment.history",
+		},
+		{
+			FromState: "in_fulfilment",
+			Event:     "cancel",
+			ToState:   "cancelled",
+		},
+	}
+
+	states := append(historyStates(fsm.DeepHistory), fsm.State{Name: "cancelled", Final: true})
+
+	// The default state of the history pseudo-state is a child of its parent
+	states[1].Initial = "packing"
+
+	sm := fsm.NewStateMachine(new(mocks.Delegate), transitions, fsm.WithStates(states), fsm.WithInitialState("new"))
+
+	assert.NoError(t, sm.Validate())
+
+	states[1].Initial = "labelling"
+
+	sm = fsm.NewStateMachine(new(mocks.Delegate), transitions, fsm.WithStates(states), fsm.WithInitialState("new"))
+
+	assert.EqualError(
+		t,
+		sm.Validate(),
+		"invalid state machine definition: default state \"labelling\" of history state \"in_fulfilment.history\" is not a child of its parent",
+	)
+}
+
+func TestStateMachine_Validate_DuplicateStates(t *testing.T) {
+	transitions := []fsm.Transition{
+		{
+			FromState: "new",
+			Event:     "go",
+			ToState:   "processing",
+		},
+		{
+			FromState: "processing",
+			Event:     "cancel",
+			ToState:   "cancelled",
+		},
+	}
+
+	states := []fsm.State{
+		{Name: "processing", Parallel: true},
+		{Name: "a", Parent: "processing"},
+		{Name: "b", Parent: "processing"},
+		{Name: "a", Parent: "processing"},
+		{Name: "cancelled", Final: true},
+	}
+
+	sm := fsm.NewStateMachine(new(mocks.Delegate), transitions, fsm.WithStates(states), fsm.WithInitialState("new"))
+
+	assert.Equal(t, []string{"duplicate_state a"}, problemKinds(t, sm.Validate()))
+
+	// The region is entered only once
+	sm = fsm.NewStateMachine(nil, transitions, fsm.WithStates(states))
+
+	nextStates, err := sm.TriggerStates([]string{"new"}, "go")
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, nextStates)
+}
+
+func TestStateMachine_Validate_NestedStates(t *testing.T) {
+	transitions := []fsm.Transition{
+		{
+			FromState: "new",
+			Event:     "place",
+			ToState:   "in_fulfilment",
+		},
+		{
+			FromState: "picking",
+			Event:     "picked",
+			ToState:   "packing",
+		},
+		{
+			FromState: "in_fulfilment",
+			Event:     "cancel",
+			ToState:   "cancelled",
+		},
+	}
+
+	states := orderStates()
+	states[3].Final = true
+
+	sm := fsm.NewStateMachine(new(mocks.Delegate), transitions, fsm.WithStates(states), fsm.WithInitialState("new"))
+
+	assert.NoError(t, sm.Validate())
+}
@@ -0,0 +1,255 @@
+package fsm_test
+
+import (
+	"testing"
+
+	"github.com/goph/fsm"
+	"github.com/goph/fsm/internal/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStateMachine_MultipleSources(t *testing.T) {
+	delegate := new(mocks.Delegate)
+	transitions := []fsm.Transition{
+		{
+			FromStates: []string{"new", "on_hold"},
+			Event:      "pay",
+			ToState:    "in_fulfilment",
+		},
+	}
+
+	delegate.On("Handle", mock.Anything, mock.Anything, "picking", []interface{}(nil)).Return(nil)
+
+	sm := fsm.NewStateMachine(delegate, transitions, fsm.WithStates(orderStates()))
+
+	for _, state := range []string{"new", "on_hold"} {
+		states, err := sm.TriggerStates([]string{state}, "pay")
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"picking"}, states)
+	}
+
+	err := sm.Trigger("cancelled", "pay")
+
+	assert.IsType(t, &fsm.InvalidTransitionError{}, err)
+}
+
+func TestStateMachine_Wildcard(t *testing.T) {
+	delegate := new(mocks.Delegate)
+	transitions := []fsm.Transition{
+		{
+			FromState:    fsm.AnyState,
+			ExceptStates: []string{"shipped", "cancelled"},
+			Event:        "cancel",
+			ToState:      "cancelled",
+			Action:       "cancel",
+		},
+	}
+
+	calls := new(recorder)
+
+	delegate.On("Handle", mock.Anything, "picking", "cancelled", []interface{}(nil)).Return(calls.record)
+	delegate.On("Handle", mock.Anything, "unknown", "cancelled", []interface{}(nil)).Return(nil)
+
+	sm := fsm.NewStateMachine(delegate, transitions, fsm.WithStates(orderStates()))
+
+	states, err := sm.TriggerStates([]string{"picking"}, "cancel")
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cancelled"}, states)
+	assert.Equal(t, []string{"exit_picking", "exit_in_fulfilment", "cancel", "enter_cancelled"}, calls.actions)
+
+	// Wildcards apply to states which are not part of the definition as well
+	err = sm.Trigger("unknown", "cancel")
+
+	require.NoError(t, err)
+
+	delegate.AssertExpectations(t)
+}
+
+func TestStateMachine_Wildcard_ExceptStates(t *testing.T) {
+	delegate := new(mocks.Delegate)
+	transitions := []fsm.Transition{
+		{
+			FromState:    fsm.AnyState,
+			ExceptStates: []string{"in_fulfilment"},
+			Event:        "cancel",
+			ToState:      "cancelled",
+		},
+	}
+
+	sm := fsm.NewStateMachine(delegate, transitions, fsm.WithStates(orderStates()))
+
+	assert.True(t, sm.Can("new", "cancel"))
+
+	// Descendants of excluded states are excluded as well
+	err := sm.Trigger("picking", "cancel")
+
+	assert.IsType(t, &fsm.InvalidTransitionError{}, err)
+}
+
+func TestStateMachine_Wildcard_ExplicitTransitionWins(t *testing.T) {
+	delegate := new(mocks.Delegate)
+	transitions := []fsm.Transition{
+		{
+			FromState: fsm.AnyState,
+			Event:     "cancel",
+			ToState:   "cancelled",
+			Action:    "cancel",
+		},
+		{
+			FromState: "packing",
+			Event:     "cancel",
+			ToState:   "packing",
+			Action:    "refuse",
+		},
+	}
+
+	calls := new(recorder)
+
+	delegate.On("Handle", mock.Anything, "packing", "packing", []interface{}(nil)).Return(calls.record)
+
+	sm := fsm.NewStateMachine(delegate, transitions, fsm.WithStates(orderStates()))
+
+	// The wildcard transition is declared first, but the explicit one wins
+	states, err := sm.TriggerStates([]string{"packing"}, "cancel")
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"packing"}, states)
+	assert.Equal(t, []string{"exit_packing", "refuse", "enter_packing"}, calls.actions)
+}
+
+func TestStateMachine_Wildcard_ExplicitTransitionOfAncestorWins(t *testing.T) {
+	transitions := []fsm.Transition{
+		{FromState: fsm.AnyState, Event: "hold", ToState: "cancelled"},
+		{FromState: "in_fulfilment", Event: "hold", ToState: "on_hold"},
+	}
+
+	sm := fsm.NewStateMachine(new(mocks.Delegate), transitions, fsm.WithStates(orderStates()))
+
+	transition, err := sm.DryRun("picking", "hold")
+
+	require.NoError(t, err)
+	assert.Equal(t, "on_hold", transition.ToState)
+}
+
+func TestStateMachine_Wildcard_Guard(t *testing.T) {
+	guard := new(mocks.Guard)
+	guard.On("Check", "can_hold", "packing", "on_hold", []interface{}(nil)).Return(false)
+	guard.On("Check", "always", "packing", "cancelled", []interface{}(nil)).Return(true)
+
+	transitions := []fsm.Transition{
+		{FromState: "packing", Event: "hold", ToState: "on_hold", Guard: "can_hold"},
+		{FromState: fsm.AnyState, Event: "hold", ToState: "cancelled", Guard: "always"},
+	}
+
+	sm := fsm.NewStateMachine(new(mocks.Delegate), transitions, fsm.WithStates(orderStates()), fsm.WithGuard(guard))
+
+	// Wildcard transitions are selected when every explicit one is rejected
+	transition, err := sm.DryRun("packing", "hold")
+
+	require.NoError(t, err)
+	assert.Equal(t, "cancelled", transition.ToState)
+
+	guard.AssertExpectations(t)
+}
+
+func TestStateMachine_Wildcard_AvailableEvents(t *testing.T) {
+	transitions := []fsm.Transition{
+		{FromState: fsm.AnyState, ExceptStates: []string{"shipped", "cancelled"}, Event: "cancel", ToState: "cancelled"},
+		{FromStates: []string{"new", "on_hold"}, Event: "pay", ToState: "in_fulfilment"},
+		{FromState: "packing", Event: "cancel", ToState: "packing"},
+		{FromState: "packing", Event: "ship", ToState: "shipped"},
+	}
+
+	sm := fsm.NewStateMachine(new(mocks.Delegate), transitions, fsm.WithStates(orderStates()))
+
+	assert.Equal(t, []string{"cancel", "ship"}, sm.AvailableEvents("packing"))
+	assert.Equal(t, []string{"pay", "cancel"}, sm.AvailableEvents("new"))
+	assert.Empty(t, sm.AvailableEvents("cancelled"))
+}
+
+func TestStateMachine_Wildcard_Validate(t *testing.T) {
+	transitions := []fsm.Transition{
+		{FromStates: []string{"new", "on_hold"}, Event: "pay", ToState: "in_fulfilment"},
+		{FromState: "picking", Event: "pick", ToState: "packing"},
+		{FromState: "packing", Event: "ship", ToState: "shipped"},
+		{FromStates: []string{"picking", "packing"}, Event: "hold", ToState: "on_hold"},
+		{FromState: fsm.AnyState, ExceptStates: []string{"shipped", "cancelled"}, Event: "cancel", ToState: "cancelled"},
+	}
+
+	sm := fsm.NewStateMachine(
+		new(mocks.Delegate),
+		transitions,
+		fsm.WithInitialState("new"),
+		fsm.WithStates(append(orderStates(), fsm.State{Name: "shipped", Final: true})),
+	)
+
+	err := sm.Validate()
+
+	require.Error(t, err)
+
+	// Every other state can be left using the wildcard transition
+	assert.Equal(t, []string{"dead_end_state cancelled"}, problemKinds(t, err))
+}
+
+func TestStateMachine_Wildcard_Validate_Invalid(t *testing.T) {
+	transitions := []fsm.Transition{
+		{FromState: fsm.AnyState, FromStates: []string{"new"}, Event: "cancel", ToState: "cancelled"},
+		{FromState: "new", ExceptStates: []string{"new"}, Event: "pay", ToState: "paid"},
+		{FromState: fsm.AnyState, ExceptStates: []string{"cancelled", "paid"}, Event: "cancel", ToState: "cancelled"},
+		{FromState: fsm.AnyState, ExceptStates: []string{"cancelled"}, Event: "archive", ToState: "archived"},
+		{FromState: fsm.AnyState, ExceptStates: []string{"cancelled", "paid"}, Event: "archive", ToState: "cancelled"},
+	}
+
+	sm := fsm.NewStateMachine(new(mocks.Delegate), transitions)
+
+	err := sm.Validate()
+
+	require.Error(t, err)
+	assert.EqualError(
+		t,
+		err,
+		"invalid state machine definition: "+
+			"wildcard transition 0 has additional from states; "+
+			"transition 1 has except states, but it is not a wildcard transition; "+
+			"transition 2 from \"*\" state triggered by \"cancel\" event is shadowed by transition 0; "+
+			"transition 4 from \"*\" state triggered by \"archive\" event is shadowed by transition 3",
+	)
+}
+
+func TestDefinition_Sources(t *testing.T) {
+	def := fsm.Definition{
+		States: orderStates(),
+		Transitions: []fsm.Transition{
+			{FromState: "new", FromStates: []string{"on_hold"}, Event: "pay", ToState: "in_fulfilment"},
+			{FromState: fsm.AnyState, ExceptStates: []string{"packing", "cancelled"}, Event: "cancel", ToState: "cancelled"},
+		},
+	}
+
+	assert.Equal(t, []string{"new", "on_hold"}, def.Sources(def.Transitions[0]))
+
+	// in_fulfilment contains an excluded state, so only its other child is a source
+	assert.Equal(t, []string{"picking", "new", "on_hold"}, def.Sources(def.Transitions[1]))
+	assert.Equal(t, []string{"in_fulfilment", "picking", "packing", "cancelled", "new", "on_hold"}, def.StateNames())
+}
+
+func TestDefinition_Preempted(t *testing.T) {
+	def := fsm.Definition{
+		States: orderStates(),
+		Transitions: []fsm.Transition{
+			{FromState: "picking", Event: "cancel", ToState: "picking"},
+			{FromState: "packing", Event: "cancel", Guard: "not_sealed", ToState: "picking"},
+			{FromState: "in_fulfilment", Event: "cancel", ToState: "cancelled"},
+			{FromState: fsm.AnyState, Event: "cancel", ToState: "cancelled"},
+		},
+	}
+
+	assert.True(t, def.Preempted(2, "picking"))
+	assert.False(t, def.Preempted(2, "packing"))
+	assert.False(t, def.Preempted(2, "in_fulfilment"))
+	assert.True(t, def.Preempted(3, "in_fulfilment"))
+	assert.False(t, def.Preempted(3, "cancelled"))
+}